@@ -12,8 +12,16 @@ type ServerConfig struct {
 }
 
 type DatabaseConfig struct {
+	Driver    string `yaml:"driver,omitempty"` // "sqlite" (défaut), "postgres" ou "mysql"
+	DSN       string `yaml:"dsn,omitempty"`    // Chaîne de connexion (obligatoire pour postgres et mysql)
 	Directory string `yaml:"directory"`
 	Name      string `yaml:"name"`
+
+	// Pool de connexions (0 ou vide : valeurs par défaut de database/sql)
+	MaxOpenConns    int    `yaml:"max_open_conns,omitempty"`
+	MaxIdleConns    int    `yaml:"max_idle_conns,omitempty"`
+	ConnMaxLifetime string `yaml:"conn_max_lifetime,omitempty"`  // Durée Go : "30m", "1h"
+	ConnMaxIdleTime string `yaml:"conn_max_idle_time,omitempty"` // Durée Go : "5m"
 }
 
 type GeneralConfig struct {
@@ -25,11 +33,27 @@ type AdminConfig struct {
 	Password string `yaml:"password"`
 }
 
+// UserConfig est un compte accepté en Basic Auth sur les écrans CRUD.
+type UserConfig struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// StorageConfig indique où sont rangés les fichiers des champs file et image.
+type StorageConfig struct {
+	Directory string `yaml:"directory,omitempty"` // Répertoire local (défaut : attachments)
+}
+
 type Config struct {
 	Server   ServerConfig   `yaml:"server"`
 	Database DatabaseConfig `yaml:"database"`
-	General  GeneralConfig  `yaml:"general"`
-	Admin    AdminConfig    `yaml:"admin"`
+	// Sources de données nommées, choisies par entité (entity.datasource) ou par requête
+	// combo / vision (datasource:) ; la base principale reste database.
+	DataSources map[string]DatabaseConfig `yaml:"datasources,omitempty"`
+	General     GeneralConfig             `yaml:"general"`
+	Admin       AdminConfig               `yaml:"admin"`
+	Users       []UserConfig              `yaml:"users,omitempty"` // Comptes des utilisateurs (vues, verrous, auteur des versions)
+	Storage     StorageConfig             `yaml:"storage,omitempty"`
 }
 
 func Load(path string) (*Config, error) {
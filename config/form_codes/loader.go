@@ -9,9 +9,13 @@ import (
 )
 
 // PrepopulateSpec décrit comment pré‐remplir un champ.
+// Type : "now", "literal", "param", "user" ou "sql".
 type PrepopulateSpec struct {
     Type   string `yaml:"type"`
-    Format string `yaml:"format"`
+    Format string `yaml:"format"` // type "now" : format de la date
+    Value  string `yaml:"value"`  // type "literal" : valeur fixe
+    Param  string `yaml:"param"`  // type "param" : nom du paramètre d'URL (par défaut le nom du champ)
+    SQL    string `yaml:"sql"`    // type "sql" : requête renvoyant une seule valeur
 }
 
 // FrontValidation règle HTML5/JS à injecter sur <input>.
@@ -33,12 +37,24 @@ type BackValidation struct {
     MaxMessage string `yaml:"max_message"`
 }
 
+// TriggerRule décrit un déclencheur évalué côté serveur à l'enregistrement.
+// Les expressions (when, set) sont écrites en CEL et portent sur les champs de l'entité.
+type TriggerRule struct {
+    Field      string            `yaml:"field"`       // on_change : champ surveillé
+    When       string            `yaml:"when"`        // Condition booléenne (facultative)
+    Set        map[string]string `yaml:"set"`         // Champ -> expression calculant sa nouvelle valeur
+    Error      string            `yaml:"error"`       // Message d'erreur levé si la condition est vraie
+    ErrorField string            `yaml:"error_field"` // Champ portant l'erreur (par défaut Field)
+}
+
 // FormCode regroupe la config de pré‐remplissage et de validation.
 type FormCode struct {
     Form             string                         `yaml:"form"`
     Prepopulate      map[string]PrepopulateSpec     `yaml:"prepopulate"`
     FrontValidations map[string]FrontValidation     `yaml:"front_validations"`
     BackValidations  map[string]BackValidation      `yaml:"back_validations"`
+    OnSave           []TriggerRule                  `yaml:"on_save"`   // Évalués à chaque enregistrement
+    OnChange         []TriggerRule                  `yaml:"on_change"` // Évalués quand Field change de valeur
 }
 
 // LoadFormCode lit et parse le YAML du code de formulaire.
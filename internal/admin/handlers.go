@@ -2,6 +2,7 @@
 package admin
 
 import (
+	"crypto/subtle"
 	"log"
 	"net/http"
 	"os"
@@ -54,11 +55,12 @@ func PostSettingsHandler(cfg *config.Config) gin.HandlerFunc {
 }
 
 // AuthMiddleware est un middleware simple pour protéger nos routes admin.
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Le login/mot de passe est codé en dur pour cet exemple.
+		// Le login/mot de passe est celui de la config admin, le même que sur les écrans CRUD.
 		user, pass, hasAuth := c.Request.BasicAuth()
-		if hasAuth && user == "admin" && pass == "password" {
+		if hasAuth && cfg.Admin.Username != "" && user == cfg.Admin.Username &&
+			subtle.ConstantTimeCompare([]byte(pass), []byte(cfg.Admin.Password)) == 1 {
 			c.Next()
 		} else {
 			c.Writer.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
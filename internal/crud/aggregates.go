@@ -0,0 +1,149 @@
+// internal/crud/aggregates.go
+package crud
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"example.com/go-crud/internal/entity"
+	"gorm.io/gorm"
+)
+
+// footerValue est un agrégat calculé, prêt à être affiché sous sa colonne.
+type footerValue struct {
+	Label string
+	Value string
+}
+
+// aggregateLabels sont les libellés par défaut des fonctions d'agrégat.
+var aggregateLabels = map[string]string{
+	"sum":   "Total",
+	"avg":   "Moyenne",
+	"min":   "Min",
+	"max":   "Max",
+	"count": "Nombre",
+}
+
+// aggregateSelect construit la liste SELECT des agrégats : SUM(champ) AS agg0, ...
+// Les champs présents dans money sont agrégés en unités mineures.
+func aggregateSelect(footer []entity.AggregateConfig, money map[string]*entity.MoneyConfig) string {
+	parts := make([]string, len(footer))
+	for i, agg := range footer {
+		col := agg.Field
+		if m, ok := money[col]; ok && agg.Func != "count" {
+			col = moneyColumn(col, m)
+		}
+		parts[i] = fmt.Sprintf("%s(%s) AS agg%d", strings.ToUpper(agg.Func), col, i)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// listFooter calcule les agrégats du pied de liste sur la requête filtrée (sans pagination).
+// Seuls les champs déclarés de l'entité sont acceptés.
+func (h *crudHandler) listFooter(q *gorm.DB) map[string][]footerValue {
+	var footer []entity.AggregateConfig
+	for _, agg := range h.ec.List.Footer {
+		if _, ok := h.ec.FieldsByName[agg.Field]; ok {
+			footer = append(footer, agg)
+		}
+	}
+	if len(footer) == 0 {
+		return nil
+	}
+	money := h.moneyFields()
+	row, err := scanAggregates(q.Session(&gorm.Session{}).Select(aggregateSelect(footer, money)), len(footer))
+	if err != nil {
+		log.Printf("[FOOTER] Erreur SQL pour %s : %v", h.ec.List.Name, err)
+		return nil
+	}
+	return h.footerValues(footer, row, money)
+}
+
+// visionFooter calcule les agrégats d'une vision en enveloppant sa requête SQL. Les
+// colonnes d'une vision n'étant pas typées, elles sont agrégées telles quelles.
+func (h *crudHandler) visionFooter(cfg entity.VisionFormConfig, args []interface{}) map[string][]footerValue {
+	if len(cfg.Footer) == 0 {
+		return nil
+	}
+	sqlText := strings.TrimRight(strings.TrimSpace(cfg.SQL), ";")
+	row, err := scanAggregates(h.source(cfg.DataSource).Raw("SELECT "+aggregateSelect(cfg.Footer, nil)+" FROM ("+sqlText+") AS vision_rows", args...), len(cfg.Footer))
+	if err != nil {
+		log.Printf("[FOOTER] Erreur SQL pour la vision %s : %v", cfg.Name, err)
+		return nil
+	}
+	return h.footerValues(cfg.Footer, row, nil)
+}
+
+// scanAggregates exécute la requête d'agrégats et renvoie les n valeurs de l'unique ligne.
+func scanAggregates(q *gorm.DB, n int) ([]interface{}, error) {
+	row := make([]interface{}, n)
+	dest := make([]interface{}, n)
+	for i := range row {
+		dest[i] = &row[i]
+	}
+	if err := q.Row().Scan(dest...); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// moneyFields renvoie la configuration des champs money de l'entité, par nom.
+func (h *crudHandler) moneyFields() map[string]*entity.MoneyConfig {
+	money := make(map[string]*entity.MoneyConfig)
+	for _, f := range h.ec.Fields {
+		if f.Money != nil {
+			money[f.Name] = f.Money
+		}
+	}
+	return money
+}
+
+// footerValues formate les résultats par colonne, avec les décimales et séparateurs du champ.
+func (h *crudHandler) footerValues(footer []entity.AggregateConfig, row []interface{}, money map[string]*entity.MoneyConfig) map[string][]footerValue {
+	values := make(map[string][]footerValue)
+	for i, agg := range footer {
+		label := agg.Label
+		if label == "" {
+			label = aggregateLabels[agg.Func]
+		}
+		values[agg.Field] = append(values[agg.Field], footerValue{
+			Label: label,
+			Value: h.formatAggregate(agg, row[i], money[agg.Field]),
+		})
+	}
+	return values
+}
+
+// formatAggregate formate la valeur d'un agrégat ; un agrégat sur un ensemble vide est affiché vide.
+// m est la devise d'un agrégat calculé en unités mineures (moyenne arrondie au centime).
+func (h *crudHandler) formatAggregate(agg entity.AggregateConfig, raw interface{}, m *entity.MoneyConfig) string {
+	if raw == nil {
+		return ""
+	}
+	if m != nil && agg.Func != "count" {
+		if minor, ok := roundMinor(raw); ok {
+			return formatMoney(minor, m)
+		}
+	}
+	if b, ok := raw.([]byte); ok {
+		raw = string(b)
+	}
+	num, err := strconv.ParseFloat(fmt.Sprint(raw), 64)
+	if err != nil {
+		return fmt.Sprint(raw) // min / max d'une chaîne ou d'une date
+	}
+	fd, hasFormat := h.ec.FicheFieldsByName[agg.Field]
+	if agg.Func == "count" {
+		thousands := " "
+		if hasFormat && fd.ThousandsSeparator != "" {
+			thousands = fd.ThousandsSeparator
+		}
+		return formatNumber(num, 0, ",", thousands)
+	}
+	if hasFormat && fd.DecimalSeparator != "" {
+		return formatNumber(num, fd.Decimals, fd.DecimalSeparator, fd.ThousandsSeparator)
+	}
+	return strings.Replace(strconv.FormatFloat(num, 'f', -1, 64), ".", ",", 1)
+}
@@ -0,0 +1,460 @@
+// internal/crud/attachments.go
+package crud
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Décodeurs des images dont une vignette est calculée
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"example.com/go-crud/internal/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FileStore range le contenu des pièces jointes. Le stockage local est utilisé par défaut ;
+// un stockage compatible S3 s'ajoute en implémentant cette interface (SetFileStore).
+type FileStore interface {
+	Save(key string, r io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Remove(key string) error
+}
+
+// files est le stockage des pièces jointes de toutes les entités.
+var files FileStore = NewLocalStore("")
+
+// SetFileStore remplace le stockage des pièces jointes.
+func SetFileStore(store FileStore) {
+	files = store
+}
+
+// localStore range les fichiers sous un répertoire local, une clé devenant un chemin relatif.
+type localStore struct {
+	dir string
+}
+
+// NewLocalStore renvoie un stockage dans le répertoire dir (défaut : attachments).
+func NewLocalStore(dir string) FileStore {
+	if dir == "" {
+		dir = "attachments"
+	}
+	return &localStore{dir: dir}
+}
+
+func (s *localStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+// Save écrit d'abord un fichier temporaire : un fichier à moitié écrit n'est jamais servi.
+func (s *localStore) Save(key string, r io.Reader) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *localStore) Open(key string) (io.ReadCloser, error) {
+	return os.Open(s.path(key))
+}
+
+func (s *localStore) Remove(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Attachment décrit une pièce jointe ; la colonne du champ file ou image contient son ID.
+// Une pièce jointe déposée reste sans RecordID jusqu'à l'enregistrement de la fiche ; une
+// pièce jointe remplacée (sans historique) ou dont l'enregistrement est supprimé est marquée
+// DeletedAt, et son fichier n'est effacé qu'après la validation de la transaction (purgeAttachments).
+type Attachment struct {
+	ID        string `gorm:"primaryKey;size:26"`
+	Entity    string `gorm:"size:100;index:idx_attachment_record"`
+	RecordID  string `gorm:"size:100;index:idx_attachment_record"`
+	Field     string `gorm:"size:100"`
+	Name      string `gorm:"size:255"` // Nom du fichier déposé
+	MimeType  string `gorm:"size:100"`
+	Size      int64
+	Thumbnail bool   // Vignette calculée (images JPEG, PNG et GIF)
+	Owner     string `gorm:"size:100"`
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// String renvoie le nom du fichier (affichage par défaut dans les listes).
+func (a *Attachment) String() string {
+	return a.Name
+}
+
+// SizeLabel renvoie la taille du fichier en octets, Ko ou Mo.
+func (a *Attachment) SizeLabel() string {
+	return sizeLabel(a.Size)
+}
+
+func (a *Attachment) key() string {
+	return a.Entity + "/" + a.ID
+}
+
+func (a *Attachment) thumbnailKey() string {
+	return a.key() + ".thumb.jpg"
+}
+
+// pendingUploadDelay est le délai au-delà duquel un fichier déposé mais jamais rattaché
+// (enregistrement abandonné ou en échec) est purgé.
+const pendingUploadDelay = time.Hour
+
+// maxThumbnailPixels borne la taille des images dont une vignette est calculée : le décodage
+// alloue l'image entière, quelle que soit la taille (compressée) du fichier déposé.
+const maxThumbnailPixels = 40_000_000
+
+func sizeLabel(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return strings.Replace(fmt.Sprintf("%.1f Mo", float64(n)/(1<<20)), ".", ",", 1)
+	case n >= 1<<10:
+		return fmt.Sprintf("%d Ko", n>>10)
+	}
+	return fmt.Sprintf("%d octets", n)
+}
+
+// uploadType renvoie le type MIME d'un fichier déposé, déterminé d'après son contenu
+// (le type annoncé par le navigateur n'est pas fiable).
+func uploadType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	mimeType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil {
+		return "application/octet-stream", nil
+	}
+	return mimeType, nil
+}
+
+// acceptedType indique si le type MIME figure dans accept ("image/*" accepte toutes les images).
+func acceptedType(accept []string, mimeType string) bool {
+	if len(accept) == 0 {
+		return true
+	}
+	for _, a := range accept {
+		if a == mimeType || strings.HasSuffix(a, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(a, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUpload vérifie le fichier déposé dans un champ file ou image (taille et type) et
+// renvoie le message d'erreur, ou "" si aucun fichier n'est déposé ou s'il est accepté.
+func checkUpload(c *gin.Context, f entity.Field) string {
+	fh, err := c.FormFile(f.Name)
+	if err != nil {
+		return ""
+	}
+	if fh.Size > f.Attachment.MaxBytes {
+		return fmt.Sprintf("Fichier trop volumineux (%s au maximum)", sizeLabel(f.Attachment.MaxBytes))
+	}
+	mimeType, err := uploadType(fh)
+	if err != nil {
+		return fmt.Sprintf("Fichier illisible : %v", err)
+	}
+	if !acceptedType(f.Attachment.Accept, mimeType) {
+		return fmt.Sprintf("Type de fichier non accepté (%s)", mimeType)
+	}
+	return ""
+}
+
+// storeUpload range le fichier déposé dans le champ f et enregistre sa pièce jointe, encore
+// rattachée à aucun enregistrement. Renvoie "" si aucun fichier n'est déposé.
+func (h *crudHandler) storeUpload(c *gin.Context, f entity.Field) (string, error) {
+	fh, err := c.FormFile(f.Name)
+	if err != nil {
+		return "", nil
+	}
+	mimeType, err := uploadType(fh)
+	if err != nil {
+		return "", err
+	}
+	a := Attachment{
+		ID:       newULID(time.Now()),
+		Entity:   h.ec.Name,
+		Field:    f.Name,
+		Name:     filepath.Base(strings.ReplaceAll(fh.Filename, "\\", "/")),
+		MimeType: mimeType,
+		Size:     fh.Size,
+		Owner:    currentUser(c),
+	}
+
+	src, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	if err := files.Save(a.key(), src); err != nil {
+		return "", err
+	}
+
+	// Vignette des images : une image que le décodeur ne sait pas lire reste sans vignette
+	if strings.HasPrefix(mimeType, "image/") {
+		if _, err := src.Seek(0, io.SeekStart); err == nil {
+			if thumb, err := thumbnail(src, f.Attachment.Thumbnail); err != nil {
+				log.Printf("[UPLOAD] Vignette de %s : %v", a.Name, err)
+			} else if err := files.Save(a.thumbnailKey(), bytes.NewReader(thumb)); err == nil {
+				a.Thumbnail = true
+			}
+		}
+	}
+
+	if err := h.db.Create(&a).Error; err != nil {
+		removeAttachmentFiles(a)
+		return "", err
+	}
+	return a.ID, nil
+}
+
+// thumbnail réduit une image pour que son plus grand côté mesure au plus size pixels
+// (moyenne des pixels source de chaque pixel réduit) et l'encode en JPEG. Les dimensions
+// sont lues avant le décodage : une image de plus de maxThumbnailPixels est refusée.
+func thumbnail(r io.ReadSeeker, size int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > maxThumbnailPixels {
+		return nil, fmt.Errorf("image de %d × %d pixels trop grande pour une vignette", cfg.Width, cfg.Height)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return nil, fmt.Errorf("image vide")
+	}
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, max(1, h*size/w)
+		} else {
+			tw, th = max(1, w*size/h), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+max((y+1)*h/th, y*h/th+1)
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+max((x+1)*w/tw, x*w/tw+1)
+			var sr, sg, sb, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					// Fond blanc sous les pixels transparents (le JPEG n'a pas de transparence)
+					sr += uint64(cr + 0xffff - ca)
+					sg += uint64(cg + 0xffff - ca)
+					sb += uint64(cb + 0xffff - ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(sr / n), G: uint16(sg / n), B: uint16(sb / n), A: 0xffff})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// attachRecord rattache à l'enregistrement id les pièces jointes déposées dans vals, et
+// marque pour suppression celles qu'elles remplacent (ou qui sont retirées de la fiche).
+// Seule une pièce jointe en attente, déposée dans le même champ, peut être rattachée : un
+// identifiant posté ne permet pas de reprendre le fichier d'un autre enregistrement. Avec
+// l'historique (entity.history), les pièces jointes remplacées restent rattachées à
+// l'enregistrement jusqu'à sa suppression, pour qu'une version restaurée les retrouve.
+func attachRecord(tx *gorm.DB, ec *entity.EntityConfig, id string, vals, previous map[string]interface{}) error {
+	for _, f := range ec.Fields {
+		v, ok := vals[f.Name]
+		if f.Attachment == nil || !ok {
+			continue
+		}
+		current := keyPart(v)
+		old := keyPart(previous[f.Name])
+		if current == old {
+			continue
+		}
+		if current != "" {
+			res := tx.Model(&Attachment{}).
+				Where("id = ? AND entity = ? AND field = ? AND record_id IN ?", current, ec.Name, f.Name, []string{"", id}).
+				Update("record_id", id)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return FieldErrors{f.Name: "Pièce jointe inconnue ou déjà rattachée à un autre enregistrement"}
+			}
+		}
+		if old != "" && !ec.History {
+			if err := tx.Where("id = ? AND entity = ? AND record_id = ?", old, ec.Name, id).Delete(&Attachment{}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// detachRecord marque pour suppression les pièces jointes d'un enregistrement supprimé.
+func detachRecord(tx *gorm.DB, ec *entity.EntityConfig, id string) error {
+	if !ec.HasAttachments() {
+		return nil
+	}
+	return tx.Where("entity = ? AND record_id = ?", ec.Name, id).Delete(&Attachment{}).Error
+}
+
+// purgeAttachments efface du stockage les fichiers des pièces jointes marquées pour
+// suppression, et ceux déposés depuis plus de pendingUploadDelay sans être rattachés.
+// Appelée après la validation des transactions qui remplacent ou suppriment des pièces jointes.
+func purgeAttachments(db *gorm.DB) {
+	var gone []Attachment
+	err := db.Unscoped().
+		Where("deleted_at IS NOT NULL OR (record_id = ? AND created_at < ?)", "", time.Now().Add(-pendingUploadDelay)).
+		Find(&gone).Error
+	if err != nil {
+		log.Printf("[UPLOAD] Purge des pièces jointes : %v", err)
+		return
+	}
+	for _, a := range gone {
+		if err := removeAttachmentFiles(a); err != nil {
+			log.Printf("[UPLOAD] Suppression de %s : %v", a.key(), err)
+			continue
+		}
+		db.Unscoped().Delete(&a)
+	}
+}
+
+func removeAttachmentFiles(a Attachment) error {
+	if a.Thumbnail {
+		if err := files.Remove(a.thumbnailKey()); err != nil {
+			return err
+		}
+	}
+	return files.Remove(a.key())
+}
+
+// loadAttachments remplace, dans les lignes lues en base, l'identifiant des champs file et
+// image par la pièce jointe (nil si elle n'existe plus), pour les liens des templates.
+func (h *crudHandler) loadAttachments(rows ...map[string]interface{}) {
+	if !h.ec.HasAttachments() {
+		return
+	}
+	var ids []string
+	for _, row := range rows {
+		for _, f := range h.ec.Fields {
+			if id := keyPart(row[f.Name]); f.Attachment != nil && id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	byID := make(map[string]*Attachment, len(ids))
+	if len(ids) > 0 {
+		var found []*Attachment
+		if err := h.db.Where("id IN ? AND entity = ?", ids, h.ec.Name).Find(&found).Error; err != nil {
+			log.Printf("[UPLOAD] Lecture des pièces jointes de %s : %v", h.ec.Name, err)
+		}
+		for _, a := range found {
+			byID[a.ID] = a
+		}
+	}
+	for _, row := range rows {
+		for _, f := range h.ec.Fields {
+			if _, ok := row[f.Name]; ok && f.Attachment != nil {
+				row[f.Name] = byID[keyPart(row[f.Name])]
+			}
+		}
+	}
+}
+
+// attachment renvoie la pièce jointe :att de l'entité.
+func (h *crudHandler) attachment(c *gin.Context) (*Attachment, bool) {
+	var a Attachment
+	if err := h.db.Where("id = ? AND entity = ?", c.Param("att"), h.ec.Name).Take(&a).Error; err != nil {
+		c.String(http.StatusNotFound, "Pièce jointe non trouvée : %v", err)
+		return nil, false
+	}
+	return &a, true
+}
+
+// downloadAttachment envoie le fichier d'une pièce jointe sous son nom d'origine.
+func (h *crudHandler) downloadAttachment(c *gin.Context) {
+	a, ok := h.attachment(c)
+	if !ok {
+		return
+	}
+	rc, err := files.Open(a.key())
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Erreur de lecture de la pièce jointe : %v", err)
+		return
+	}
+	defer rc.Close()
+	c.DataFromReader(http.StatusOK, a.Size, a.MimeType, rc, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}),
+	})
+}
+
+// attachmentThumbnail envoie la vignette d'une image.
+func (h *crudHandler) attachmentThumbnail(c *gin.Context) {
+	a, ok := h.attachment(c)
+	if !ok {
+		return
+	}
+	if !a.Thumbnail {
+		c.String(http.StatusNotFound, "Pas de vignette pour %s", a.Name)
+		return
+	}
+	rc, err := files.Open(a.thumbnailKey())
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Erreur de lecture de la vignette : %v", err)
+		return
+	}
+	defer rc.Close()
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.DataFromReader(http.StatusOK, -1, "image/jpeg", rc, nil)
+}
@@ -0,0 +1,181 @@
+// internal/crud/attachments_test.go
+package crud
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/go-crud/internal/entity"
+)
+
+func TestAcceptedType(t *testing.T) {
+	tests := []struct {
+		accept []string
+		mime   string
+		want   bool
+	}{
+		{nil, "application/x-msdownload", true}, // Tous les types par défaut
+		{[]string{"application/pdf"}, "application/pdf", true},
+		{[]string{"application/pdf"}, "application/pdfx", false},
+		{[]string{"application/pdf", "image/*"}, "image/png", true},
+		{[]string{"image/*"}, "image/svg+xml", true},
+		{[]string{"image/*"}, "imagex/png", false},
+		{[]string{"image/*"}, "text/html", false},
+		{[]string{"image/png"}, "image/jpeg", false},
+	}
+	for _, tc := range tests {
+		if got := acceptedType(tc.accept, tc.mime); got != tc.want {
+			t.Errorf("acceptedType(%v, %s) = %v, attendu %v", tc.accept, tc.mime, got, tc.want)
+		}
+	}
+}
+
+// encodeImage renvoie une image unie de w × h pixels au format demandé.
+func encodeImage(t *testing.T, format string, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: 200, G: 10, B: 10, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pngHeader renvoie l'en-tête d'un PNG annonçant w × h pixels, sans données d'image : assez
+// pour DecodeConfig, pas pour un décodage complet.
+func pngHeader(t *testing.T, w, h int) []byte {
+	t.Helper()
+	data := encodeImage(t, "png", 1, 1)
+	// Largeur et hauteur de l'en-tête IHDR, puis son CRC (type et données du bloc)
+	binary.BigEndian.PutUint32(data[16:20], uint32(w))
+	binary.BigEndian.PutUint32(data[20:24], uint32(h))
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantW   int
+		wantH   int
+		wantErr string
+	}{
+		{"paysage réduit", encodeImage(t, "png", 400, 200), 160, 80, ""},
+		{"portrait réduit", encodeImage(t, "jpeg", 100, 500), 32, 160, ""},
+		{"petite image gardée", encodeImage(t, "gif", 20, 10), 20, 10, ""},
+		{"ligne très fine", encodeImage(t, "png", 1000, 1), 160, 1, ""},
+		{"trop de pixels", pngHeader(t, 30000, 30000), 0, 0, "trop grande"},
+		{"pas une image", []byte("%PDF-1.4"), 0, 0, "unknown format"},
+	}
+	for _, tc := range tests {
+		out, err := thumbnail(bytes.NewReader(tc.data), 160)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s : thumbnail = %v, erreur attendue contenant %q", tc.name, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s : thumbnail : %v", tc.name, err)
+			continue
+		}
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(out))
+		if err != nil || format != "jpeg" || cfg.Width != tc.wantW || cfg.Height != tc.wantH {
+			t.Errorf("%s : vignette %s %d × %d (%v), attendu jpeg %d × %d", tc.name, format, cfg.Width, cfg.Height, err, tc.wantW, tc.wantH)
+		}
+	}
+}
+
+func TestAttachRecord(t *testing.T) {
+	db := openDialect(t, "sqlite")
+	if err := db.AutoMigrate(&Attachment{}); err != nil {
+		t.Fatal(err)
+	}
+	field := entity.Field{Name: "releve", Type: "file", Attachment: &entity.AttachmentConfig{}}
+	other := entity.Field{Name: "scan", Type: "file", Attachment: &entity.AttachmentConfig{}}
+	plain := &entity.EntityConfig{Name: "compte", Fields: []entity.Field{field, other}}
+	versioned := &entity.EntityConfig{Name: "compte", Fields: []entity.Field{field, other}, History: true}
+	for _, a := range []Attachment{
+		{ID: "A1", Entity: "compte", Field: "releve"},                // En attente
+		{ID: "A2", Entity: "compte", Field: "releve"},                // En attente
+		{ID: "S1", Entity: "compte", Field: "scan"},                  // En attente, autre champ
+		{ID: "B1", Entity: "compte", Field: "releve", RecordID: "2"}, // Rattachée à un autre enregistrement
+		{ID: "X1", Entity: "facture", Field: "releve"},               // Autre entité
+		{ID: "H1", Entity: "compte", Field: "releve"},
+		{ID: "H2", Entity: "compte", Field: "releve"},
+	} {
+		if err := db.Create(&a).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	steps := []struct {
+		name     string
+		ec       *entity.EntityConfig
+		id       string
+		vals     map[string]interface{}
+		previous map[string]interface{}
+		wantErr  bool
+	}{
+		{"rattachement", plain, "1", map[string]interface{}{"releve": "A1"}, nil, false},
+		{"pièce jointe d'un autre enregistrement", plain, "1", map[string]interface{}{"releve": "B1"}, map[string]interface{}{"releve": "A1"}, true},
+		{"pièce jointe d'un autre champ", plain, "1", map[string]interface{}{"releve": "S1"}, map[string]interface{}{"releve": "A1"}, true},
+		{"pièce jointe d'une autre entité", plain, "1", map[string]interface{}{"releve": "X1"}, map[string]interface{}{"releve": "A1"}, true},
+		{"remplacement", plain, "1", map[string]interface{}{"releve": "A2"}, map[string]interface{}{"releve": "A1"}, false},
+		{"historique : rattachement", versioned, "3", map[string]interface{}{"releve": "H1"}, nil, false},
+		{"historique : remplacement", versioned, "3", map[string]interface{}{"releve": "H2"}, map[string]interface{}{"releve": "H1"}, false},
+		{"historique : version restaurée", versioned, "3", map[string]interface{}{"releve": "H1"}, map[string]interface{}{"releve": "H2"}, false},
+		{"historique : pièce jointe d'un autre enregistrement", versioned, "3", map[string]interface{}{"releve": "B1"}, map[string]interface{}{"releve": "H1"}, true},
+	}
+	for _, s := range steps {
+		err := attachRecord(db, s.ec, s.id, s.vals, s.previous)
+		if _, ok := asFieldErrors(err); ok != s.wantErr || err != nil && !ok {
+			t.Errorf("%s : attachRecord = %v", s.name, err)
+		}
+	}
+
+	// Rattachées : A2 (A1 remplacée et supprimée), H1 et H2 (conservées par l'historique)
+	var live []string
+	db.Model(&Attachment{}).Where("record_id <> ?", "").Order("id").Pluck("id", &live)
+	if want := []string{"A2", "B1", "H1", "H2"}; !reflect.DeepEqual(live, want) {
+		t.Errorf("pièces jointes rattachées %v, attendu %v", live, want)
+	}
+	var deleted []string
+	db.Unscoped().Model(&Attachment{}).Where("deleted_at IS NOT NULL").Pluck("id", &deleted)
+	if want := []string{"A1"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("pièces jointes supprimées %v, attendu %v", deleted, want)
+	}
+
+	// La suppression de l'enregistrement libère aussi les pièces jointes conservées
+	if err := detachRecord(db, versioned, "3"); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Model(&Attachment{}).Where("record_id = ?", "3").Count(&count)
+	if count != 0 {
+		t.Errorf("%d pièces jointes restent rattachées à l'enregistrement supprimé", count)
+	}
+}
@@ -0,0 +1,86 @@
+// internal/crud/audit.go
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/go-crud/internal/entity"
+	"gorm.io/gorm"
+)
+
+// AuditEntry est une ligne du journal des modifications. Elle est écrite dans la même
+// transaction que l'enregistrement : une opération annulée ne laisse aucune trace.
+type AuditEntry struct {
+	ID        uint   `gorm:"primaryKey"`
+	Entity    string `gorm:"index:idx_audit_record;size:100"`
+	RecordID  string `gorm:"index:idx_audit_record;size:100"`
+	Action    string `gorm:"size:10"` // "create", "update" ou "delete"
+	Owner     string `gorm:"size:100"`
+	Changes   string // JSON : valeurs créées, {champ: [avant, après]} ou enregistrement supprimé
+	CreatedAt time.Time
+}
+
+// writeAudit ajoute une entrée au journal dans la transaction tx.
+func writeAudit(tx *gorm.DB, ec *entity.EntityConfig, id, action, user string, changes interface{}) error {
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&AuditEntry{
+		Entity:   ec.Name,
+		RecordID: id,
+		Action:   action,
+		Owner:    user,
+		Changes:  string(b),
+	}).Error
+}
+
+// auditValues normalise les valeurs d'un enregistrement pour le journal ([]byte -> texte),
+// sans les clés techniques ajoutées par GORM à la création ("@id").
+func auditValues(values map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		if strings.HasPrefix(k, "@") {
+			continue
+		}
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// auditChanges renvoie les champs modifiés, avec leur valeur avant et après.
+func auditChanges(previous, updates map[string]interface{}) map[string][2]interface{} {
+	changes := make(map[string][2]interface{})
+	for k, v := range auditValues(updates) {
+		old := previous[k]
+		if b, ok := old.([]byte); ok {
+			old = string(b)
+		}
+		if auditString(old) != auditString(v) {
+			changes[k] = [2]interface{}{old, v}
+		}
+	}
+	return changes
+}
+
+// auditString compare les valeurs indépendamment de leur type Go (int64 lu, int saisi...).
+func auditString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return t.Format("2006-01-02 15:04:05")
+	case bool:
+		if t {
+			return "1"
+		}
+		return "0"
+	}
+	return fmt.Sprint(v)
+}
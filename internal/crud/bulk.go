@@ -0,0 +1,207 @@
+// internal/crud/bulk.go
+package crud
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/go-crud/internal/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// bulkFailure décrit un enregistrement sur lequel une action groupée a échoué.
+type bulkFailure struct {
+	ID      string
+	Message string
+}
+
+// runBulk applique op à chaque identifiant dans une transaction unique. Chaque ligne
+// est isolée dans un point de sauvegarde : une ligne en échec est annulée et reportée
+// sans empêcher le traitement des autres.
+func (h *crudHandler) runBulk(ids []string, op func(tx *gorm.DB, id string) error) (int, []bulkFailure, error) {
+	done := 0
+	var failures []bulkFailure
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			err := tx.Transaction(func(sp *gorm.DB) error {
+				return op(sp, id)
+			})
+			if err != nil {
+				failures = append(failures, bulkFailure{ID: id, Message: err.Error()})
+				continue
+			}
+			done++
+		}
+		return nil
+	})
+	return done, failures, err
+}
+
+// bulkDelete supprime les enregistrements sélectionnés dans la liste.
+func (h *crudHandler) bulkDelete(c *gin.Context) {
+	if h.ec.List.Bulk == nil || !h.ec.List.Bulk.Delete {
+		c.String(http.StatusForbidden, "Suppression groupée non autorisée")
+		return
+	}
+	ids := c.PostFormArray("ids")
+	user := currentUser(c)
+	done, failures, err := h.runBulk(ids, func(tx *gorm.DB, id string) error {
+		return deleteWithReferences(tx, h.ec, id, user, map[string]bool{})
+	})
+	purgeAttachments(h.db)
+	h.renderBulkReport(c, "Suppression groupée", len(ids), done, failures, err)
+}
+
+// bulkUpdate affecte une même valeur à un champ de tous les enregistrements sélectionnés.
+// La valeur est validée et convertie comme dans la fiche ; hooks, déclencheurs et
+// contraintes sont appliqués ligne par ligne.
+func (h *crudHandler) bulkUpdate(c *gin.Context) {
+	field := c.PostForm("field")
+	if !h.bulkUpdatable(field) {
+		c.String(http.StatusBadRequest, "Modification groupée non autorisée pour le champ %s", field)
+		return
+	}
+	raw := c.PostForm("value")
+	if h.ec.Code != nil {
+		if rule, ok := h.ec.Code.BackValidations[field]; ok {
+			if msg := validateValue(rule, raw); msg != "" {
+				h.renderBulkReport(c, "Modification groupée", 0, 0, nil, fmt.Errorf("%s", msg))
+				return
+			}
+		}
+	}
+	msg := checkEnumValue(h.ec.FieldsByName[field], raw)
+	if msg == "" {
+		msg = checkMoneyValue(h.ec.FieldsByName[field], raw)
+	}
+	if msg != "" {
+		h.renderBulkReport(c, "Modification groupée", 0, 0, nil, fmt.Errorf("%s", msg))
+		return
+	}
+	value := convertFormValue(h.ec.FieldsByName[field], h.ec.FicheFieldsByName[field], raw)
+
+	ids := c.PostFormArray("ids")
+	user := currentUser(c)
+	done, failures, err := h.runBulk(ids, func(tx *gorm.DB, id string) error {
+		return h.updateRecord(tx, user, id, map[string]interface{}{field: value})
+	})
+	h.renderBulkReport(c, "Modification groupée : "+fieldLabel(h.ec, field), len(ids), done, failures, err)
+}
+
+// bulkUpdatable indique si le champ fait partie des champs modifiables en masse.
+func (h *crudHandler) bulkUpdatable(field string) bool {
+	if h.ec.List.Bulk == nil {
+		return false
+	}
+	for _, f := range h.ec.List.Bulk.UpdateFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// bulkExport renvoie les enregistrements sélectionnés au format CSV (séparateur ';',
+// nombres et dates au format de la fiche), avec les libellés des champs en en-tête.
+func (h *crudHandler) bulkExport(c *gin.Context) {
+	if h.ec.List.Bulk == nil || !h.ec.List.Bulk.Export {
+		c.String(http.StatusForbidden, "Export non autorisé")
+		return
+	}
+	ids := c.PostFormArray("ids")
+	var rows []map[string]interface{}
+	if len(ids) > 0 {
+		where, args, err := keysCondition(h.ec, ids)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Erreur d'export : %v", err)
+			return
+		}
+		if err := h.db.Table(h.ec.Table).Where(where, args...).Order(keyOrder(h.ec, "ASC")).Find(&rows).Error; err != nil {
+			c.String(http.StatusInternalServerError, "Erreur d'export : %v", err)
+			return
+		}
+	}
+
+	filename := fmt.Sprintf("%s-%s.csv", h.ec.Name, time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Writer.WriteString("\uFEFF") // BOM pour l'ouverture directe dans Excel
+
+	w := csv.NewWriter(c.Writer)
+	w.Comma = ';'
+	header := make([]string, len(h.ec.Fields))
+	for i, f := range h.ec.Fields {
+		header[i] = fieldLabel(h.ec, f.Name)
+	}
+	w.Write(header)
+	for _, row := range rows {
+		record := make([]string, len(h.ec.Fields))
+		for i, f := range h.ec.Fields {
+			record[i] = h.exportValue(f, row[f.Name])
+		}
+		w.Write(record)
+	}
+	w.Flush()
+}
+
+// exportValue formate une valeur pour l'export CSV.
+func (h *crudHandler) exportValue(f entity.Field, raw interface{}) string {
+	if raw == nil {
+		return ""
+	}
+	// Le pilote MySQL renvoie les valeurs textuelles en []byte
+	if b, ok := raw.([]byte); ok {
+		raw = string(b)
+	}
+	switch f.Type {
+	case "enum":
+		return enumLabel(f, raw)
+	case "money": // Montant sans symbole, la devise est celle de la colonne
+		if minor, ok := moneyValue(raw, f.Money); ok {
+			return moneyAmount(minor, f.Money)
+		}
+	case "boolean":
+		switch v := raw.(type) {
+		case bool:
+			if v {
+				return "1"
+			}
+			return "0"
+		}
+	case "number":
+		if num, err := strconv.ParseFloat(fmt.Sprint(raw), 64); err == nil {
+			if fd, ok := h.ec.FicheFieldsByName[f.Name]; ok && fd.DecimalSeparator != "" {
+				return formatNumber(num, fd.Decimals, fd.DecimalSeparator, fd.ThousandsSeparator)
+			}
+			return strings.Replace(strconv.FormatFloat(num, 'f', -1, 64), ".", ",", 1)
+		}
+	case "date", "datetime":
+		if t, ok := raw.(time.Time); ok && f.DisplayFormat != "" {
+			return t.Format(f.DisplayFormat)
+		}
+	}
+	return fmt.Sprint(raw)
+}
+
+// renderBulkReport affiche le compte rendu d'une action groupée.
+func (h *crudHandler) renderBulkReport(c *gin.Context, title string, selected, done int, failures []bulkFailure, err error) {
+	// Retour vers la liste dans l'état où elle était (pagination, tri, recherche)
+	back := "/" + h.ec.List.Name
+	if q := c.PostForm("back"); strings.HasPrefix(q, "?") {
+		back += q
+	}
+	c.HTML(http.StatusOK, "bulk_report.html", gin.H{
+		"Entity":   h.ec,
+		"Title":    title,
+		"Selected": selected,
+		"Done":     done,
+		"Failures": failures,
+		"Error":    err,
+		"Back":     back,
+	})
+}
@@ -0,0 +1,133 @@
+// internal/crud/bulk_test.go
+package crud
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/go-crud/config/form_codes"
+	"example.com/go-crud/internal/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// bulkHandler renvoie le handler d'une table compte (nom, statut enum, solde en centimes)
+// contenant trois enregistrements.
+func bulkHandler(t *testing.T) *crudHandler {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := openDialect(t, "sqlite")
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		"CREATE TABLE compte (id INTEGER PRIMARY KEY, nom TEXT, statut TEXT, solde INTEGER CHECK (solde >= 0))",
+		"INSERT INTO compte VALUES (1, 'A', 'ouvert', 100), (2, 'B', 'ouvert', 20), (3, 'C', 'ouvert', 300)",
+	} {
+		if err := db.Exec(q).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	fields := []entity.Field{
+		{Name: "id", Type: "int"},
+		{Name: "nom", Type: "string"},
+		{Name: "statut", Type: "enum", Enum: &entity.EnumConfig{Values: []entity.EnumValue{{Value: "ouvert"}, {Value: "clos"}}}},
+		{Name: "solde", Type: "money", Money: &entity.MoneyConfig{Currency: "EUR", Storage: "minor", Digits: 2}},
+	}
+	ec := &entity.EntityConfig{
+		Name:         "compte",
+		Table:        "compte",
+		PrimaryKey:   []string{"id"},
+		Fields:       fields,
+		FieldsByName: make(map[string]entity.Field),
+		Code: &form_codes.FormCode{BackValidations: map[string]form_codes.BackValidation{
+			"nom": {Max: 3, MaxMessage: "Nom trop long"},
+		}},
+	}
+	for _, f := range fields {
+		ec.FieldsByName[f.Name] = f
+	}
+	ec.List.Name = "compteList"
+	ec.List.Bulk = &entity.BulkConfig{UpdateFields: []string{"nom", "statut", "solde"}}
+	return &crudHandler{db: db, ec: ec}
+}
+
+// column renvoie les valeurs d'une colonne de la table compte, par clé.
+func column(t *testing.T, h *crudHandler, name string) []string {
+	t.Helper()
+	var got []string
+	if err := h.db.Table("compte").Order("id").Pluck(name, &got).Error; err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+// Une ligne en échec est annulée seule (point de sauvegarde) : les autres sont enregistrées.
+func TestRunBulk(t *testing.T) {
+	h := bulkHandler(t)
+	// Deux écritures par ligne : le nom, puis un débit refusé par la contrainte CHECK si le solde
+	// est insuffisant ; le nom de la ligne refusée ne doit pas être modifié
+	done, failures, err := h.runBulk([]string{"1", "2", "3"}, func(tx *gorm.DB, id string) error {
+		if err := tx.Exec("UPDATE compte SET nom = nom || '*' WHERE id = ?", id).Error; err != nil {
+			return err
+		}
+		if id == "3" {
+			return errors.New("refusé par un hook")
+		}
+		return tx.Exec("UPDATE compte SET solde = solde - 50 WHERE id = ?", id).Error
+	})
+	if err != nil || done != 1 || len(failures) != 2 {
+		t.Fatalf("runBulk = %d, %v, %v ; attendu 1 ligne traitée et 2 échecs", done, failures, err)
+	}
+	if failures[0].ID != "2" || !strings.Contains(failures[0].Message, "CHECK") || failures[1] != (bulkFailure{ID: "3", Message: "refusé par un hook"}) {
+		t.Errorf("échecs %v", failures)
+	}
+	if got, want := column(t, h, "nom"), []string{"A*", "B", "C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("noms %q, attendu %q", got, want)
+	}
+	if got, want := column(t, h, "solde"), []string{"50", "20", "300"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("soldes %q, attendu %q", got, want)
+	}
+}
+
+// La valeur d'une modification groupée est validée comme dans la fiche (règle, enum, montant)
+// avant toute écriture, puis convertie.
+func TestBulkUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		value   string
+		wantErr string // Message du compte rendu, "" si la modification est appliquée
+		column  []string
+	}{
+		{"règle de validation", "nom", "Dupont", "Nom trop long", []string{"A", "B", "C"}},
+		{"valeur hors enum", "statut", "perdu", "Valeur « perdu » non autorisée", []string{"ouvert", "ouvert", "ouvert"}},
+		{"montant invalide", "solde", "douze", "Montant « douze » invalide", []string{"100", "20", "300"}},
+		{"valeur enum", "statut", "clos", "", []string{"clos", "clos", "ouvert"}},
+		{"montant converti en centimes", "solde", "12,50", "", []string{"1250", "1250", "300"}},
+	}
+	for _, tc := range tests {
+		h := bulkHandler(t)
+		form := url.Values{"field": {tc.field}, "value": {tc.value}, "ids": {"1", "2"}}
+		status, fc := postForm(t, h.bulkUpdate, "/compteList/bulk/update", "", form)
+		if status != http.StatusOK || fc.name != "bulk_report.html" {
+			t.Errorf("%s : statut %d, gabarit %q", tc.name, status, fc.name)
+			continue
+		}
+		err, _ := fc.data["Error"].(error)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) || fc.data["Done"] != 0 {
+				t.Errorf("%s : compte rendu %v (%v traités), attendu %q", tc.name, err, fc.data["Done"], tc.wantErr)
+			}
+		} else if err != nil || fc.data["Done"] != 2 || len(fc.data["Failures"].([]bulkFailure)) > 0 {
+			t.Errorf("%s : compte rendu %v, %v traités, échecs %v", tc.name, err, fc.data["Done"], fc.data["Failures"])
+		}
+		if got := column(t, h, tc.field); !reflect.DeepEqual(got, tc.column) {
+			t.Errorf("%s : %s = %q, attendu %q", tc.name, tc.field, got, tc.column)
+		}
+	}
+}
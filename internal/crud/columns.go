@@ -0,0 +1,173 @@
+// internal/crud/columns.go
+package crud
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// ColumnPreference mémorise, pour un utilisateur et une liste, les colonnes affichées
+// (dans l'ordre choisi) et leurs largeurs.
+type ColumnPreference struct {
+	ID      uint   `gorm:"primaryKey"`
+	List    string `gorm:"uniqueIndex:idx_column_pref;size:100"`
+	Owner   string `gorm:"uniqueIndex:idx_column_pref;size:100"`
+	Columns string // Noms des champs séparés par des virgules
+	Widths  string // Largeurs CSS correspondantes, séparées par des virgules
+}
+
+// columnChoice est une ligne du sélecteur de colonnes de la liste.
+type columnChoice struct {
+	Field   string
+	Label   string
+	Visible bool
+	Width   string
+}
+
+// cssWidth n'accepte que des largeurs CSS simples ("120px", "15%", "10rem", "auto").
+var cssWidth = regexp.MustCompile(`^(auto|\d+(\.\d+)?(px|%|rem|em))$`)
+
+// knownColumns ne garde que les champs déclarés de l'entité : seuls ceux-ci peuvent
+// être passés au Select de la requête de liste.
+func (h *crudHandler) knownColumns(names []string) []string {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if _, ok := h.ec.FieldsByName[name]; ok && !seen[name] {
+			columns = append(columns, name)
+			seen[name] = true
+		}
+	}
+	return columns
+}
+
+// listColumns renvoie les colonnes à afficher et leurs largeurs, par ordre de priorité :
+// paramètre "cols" (vue), préférence enregistrée de l'utilisateur, colonnes du YAML.
+// Le booléen indique si les colonnes proviennent d'une préférence enregistrée.
+func (h *crudHandler) listColumns(c *gin.Context) ([]string, []string, bool) {
+	if cols := c.Query("cols"); cols != "" {
+		if columns := h.knownColumns(strings.Split(cols, ",")); len(columns) > 0 {
+			return columns, nil, false
+		}
+	}
+	if pref, ok := h.columnPreference(c); ok {
+		columns := h.knownColumns(strings.Split(pref.Columns, ","))
+		if len(columns) > 0 {
+			widths := strings.Split(pref.Widths, ",")
+			byName := make(map[string]string)
+			for i, name := range strings.Split(pref.Columns, ",") {
+				if i < len(widths) {
+					byName[name] = widths[i]
+				}
+			}
+			out := make([]string, len(columns), len(columns)+1)
+			for i, name := range columns {
+				out[i] = byName[name]
+				if out[i] == "" {
+					out[i] = "auto"
+				}
+			}
+			// La largeur de la colonne Actions reste celle du YAML
+			if yw := h.ec.List.ColumnWidths; len(yw) > len(h.ec.List.Columns) {
+				out = append(out, yw[len(h.ec.List.Columns)])
+			}
+			return columns, out, true
+		}
+	}
+	return h.ec.List.Columns, h.ec.List.ColumnWidths, false
+}
+
+// columnPreference charge la préférence de colonnes de l'utilisateur courant pour la liste.
+func (h *crudHandler) columnPreference(c *gin.Context) (ColumnPreference, bool) {
+	var pref ColumnPreference
+	err := h.db.Where("list = ? AND owner = ?", h.ec.List.Name, currentUser(c)).Take(&pref).Error
+	return pref, err == nil
+}
+
+// columnChoices construit le sélecteur : colonnes affichées dans leur ordre, puis les autres
+// champs de l'entité dans l'ordre du YAML.
+func (h *crudHandler) columnChoices(columns, widths []string) []columnChoice {
+	choices := make([]columnChoice, 0, len(h.ec.Fields))
+	shown := make(map[string]bool)
+	for i, name := range columns {
+		width := ""
+		if i < len(widths) && widths[i] != "auto" {
+			width = widths[i]
+		}
+		choices = append(choices, columnChoice{Field: name, Label: fieldLabel(h.ec, name), Visible: true, Width: width})
+		shown[name] = true
+	}
+	for _, f := range h.ec.Fields {
+		if !shown[f.Name] {
+			choices = append(choices, columnChoice{Field: f.Name, Label: fieldLabel(h.ec, f.Name)})
+		}
+	}
+	return choices
+}
+
+// saveColumns enregistre les colonnes cochées (dans l'ordre du formulaire) et leurs largeurs
+// pour l'utilisateur courant. Un formulaire avec reset=1 revient aux colonnes du YAML.
+func (h *crudHandler) saveColumns(c *gin.Context) {
+	back := "/" + h.ec.List.Name
+	if q := c.PostForm("back"); strings.HasPrefix(q, "?") {
+		back += q
+	}
+	owner := currentUser(c)
+
+	if c.PostForm("reset") != "" {
+		if err := h.db.Where("list = ? AND owner = ?", h.ec.List.Name, owner).Delete(&ColumnPreference{}).Error; err != nil {
+			c.String(http.StatusInternalServerError, "Erreur de réinitialisation des colonnes : %v", err)
+			return
+		}
+		c.Redirect(http.StatusSeeOther, back)
+		return
+	}
+
+	columns := h.knownColumns(c.PostFormArray("cols"))
+	if len(columns) == 0 {
+		c.String(http.StatusBadRequest, "Au moins une colonne doit être affichée")
+		return
+	}
+	widths := make([]string, len(columns))
+	for i, name := range columns {
+		widths[i] = "auto"
+		if w := strings.TrimSpace(c.PostForm("width_" + name)); cssWidth.MatchString(w) {
+			widths[i] = w
+		}
+	}
+
+	pref := ColumnPreference{
+		List:    h.ec.List.Name,
+		Owner:   owner,
+		Columns: strings.Join(columns, ","),
+		Widths:  strings.Join(widths, ","),
+	}
+	err := h.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "list"}, {Name: "owner"}},
+		DoUpdates: clause.AssignmentColumns([]string{"columns", "widths"}),
+	}).Create(&pref).Error
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Erreur d'enregistrement des colonnes : %v", err)
+		return
+	}
+	c.Redirect(http.StatusSeeOther, back)
+}
+
+// sortColumn renvoie le champ de tri demandé s'il fait partie des champs de l'entité,
+// sinon le tri par défaut de la liste ; l'ordre est limité à asc / desc.
+func (h *crudHandler) sortColumn(c *gin.Context) (string, string) {
+	field := c.DefaultQuery("sort", h.ec.List.DefaultSortField)
+	if _, ok := h.ec.FieldsByName[field]; !ok {
+		field = h.ec.List.DefaultSortField
+	}
+	order := strings.ToLower(c.DefaultQuery("order", h.ec.List.DefaultSortOrder))
+	if order != "asc" && order != "desc" {
+		order = "asc"
+	}
+	return field, order
+}
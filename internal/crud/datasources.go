@@ -0,0 +1,71 @@
+// internal/crud/datasources.go
+package crud
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// dataSources contient les sources de données nommées (config datasources), pour les
+// requêtes combo / vision qui déclarent `datasource:`.
+var dataSources = make(map[string]*gorm.DB)
+
+// RegisterDataSource rend une source de données nommée disponible pour les requêtes SQL
+// du YAML. À appeler avant RegisterEntity.
+func RegisterDataSource(name string, db *gorm.DB) {
+	dataSources[name] = db
+}
+
+// source renvoie la base d'une requête du YAML : la source nommée, ou celle de l'entité
+// si le nom est vide ou inconnu (l'erreur est signalée à l'enregistrement de l'entité).
+func (h *crudHandler) source(name string) *gorm.DB {
+	if name == "" {
+		return h.db
+	}
+	if db, ok := dataSources[name]; ok {
+		return db
+	}
+	return h.db
+}
+
+// tableDB renvoie la base de l'entité enregistrée qui gère la table, nil si aucune.
+func tableDB(table string) *gorm.DB {
+	for _, h := range searchHandlers {
+		if h.ec.Table == table {
+			return h.db
+		}
+	}
+	return nil
+}
+
+// refDB renvoie la base d'une table référencée : celle de son entité, sinon celle de l'entité courante.
+func (h *crudHandler) refDB(table string) *gorm.DB {
+	if db := tableDB(table); db != nil {
+		return db
+	}
+	return h.db
+}
+
+// checkDataSources signale au démarrage les sources nommées inconnues du YAML de l'entité.
+func (h *crudHandler) checkDataSources() {
+	var names []string
+	for _, grp := range h.ec.Fiche.Groups {
+		for _, fd := range grp.Fields {
+			if fd.ComboConfig != nil {
+				names = append(names, fd.ComboConfig.DataSource)
+			}
+			if fd.VisionConfig != nil {
+				names = append(names, fd.VisionConfig.DataSource)
+			}
+		}
+	}
+	for _, v := range h.ec.VisionForms {
+		names = append(names, v.DataSource)
+	}
+	for _, name := range names {
+		if _, ok := dataSources[name]; name != "" && !ok {
+			log.Printf("[DATASOURCE] Source de données inconnue « %s » dans %s : base de l'entité utilisée", name, h.ec.Name)
+		}
+	}
+}
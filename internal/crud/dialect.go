@@ -0,0 +1,96 @@
+// internal/crud/dialect.go
+package crud
+
+import (
+	"fmt"
+	"regexp"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Les requêtes des handlers restent en SQL portable ; les quelques différences entre
+// SQLite, PostgreSQL et MySQL sont regroupées ici.
+
+// dialect renvoie le nom du pilote GORM : "sqlite", "postgres" ou "mysql".
+func dialect(db *gorm.DB) string {
+	return db.Dialector.Name()
+}
+
+// likeCondition renvoie la condition « champ contient » : LIKE est déjà insensible à la
+// casse sous SQLite et MySQL ; PostgreSQL demande ILIKE et une conversion en texte des
+// colonnes non textuelles (recherche sur l'id par exemple).
+func likeCondition(db *gorm.DB, field string, textual bool) string {
+	if dialect(db) != "postgres" {
+		return field + " LIKE ?"
+	}
+	if !textual {
+		return "CAST(" + field + " AS TEXT) ILIKE ?"
+	}
+	return field + " ILIKE ?"
+}
+
+// textualType indique si un type de champ est stocké en texte.
+func textualType(fieldType string) bool {
+	switch fieldType {
+	case "uint", "int", "number", "money", "boolean", "date", "datetime", "enum": // Un enum peut coder ses valeurs en nombres
+		return false
+	}
+	return true
+}
+
+// nullsFirst complète un tri pour que NULL soit la plus petite valeur, comme sous SQLite
+// et MySQL (PostgreSQL place les NULL en fin de tri croissant).
+func nullsFirst(db *gorm.DB, dir string) string {
+	if dialect(db) != "postgres" {
+		return dir
+	}
+	if dir == "DESC" {
+		return dir + " NULLS LAST"
+	}
+	return dir + " NULLS FIRST"
+}
+
+// insertRow insère une ligne et renvoie les valeurs des colonnes de la clé : RETURNING sous
+// SQLite et PostgreSQL, LastInsertId sous MySQL (clé "@id" posée par GORM) pour une clé
+// générée, valeurs insérées sinon. La clé n'est jamais devinée en relisant la table, ce qui
+// serait faux en cas d'insertions concurrentes.
+func insertRow(tx *gorm.DB, table string, key []string, vals map[string]interface{}) ([]string, error) {
+	q := tx.Table(table)
+	if dialect(tx) != "mysql" {
+		cols := make([]clause.Column, len(key))
+		for i, col := range key {
+			cols[i] = clause.Column{Name: col}
+		}
+		q = q.Clauses(clause.Returning{Columns: cols})
+	}
+	if err := q.Create(vals).Error; err != nil {
+		return nil, err
+	}
+	generated := vals["@id"]
+	delete(vals, "@id")
+	parts := make([]string, len(key))
+	for i, col := range key {
+		v := vals[col]
+		if v == nil && len(key) == 1 {
+			v = generated
+		}
+		if v == nil {
+			return nil, fmt.Errorf("la base n'a pas renvoyé la clé de l'enregistrement créé")
+		}
+		parts[i] = keyPart(v)
+	}
+	return parts, nil
+}
+
+// colonParam repère les paramètres nommés « :nom » des requêtes SQL du YAML (hors « :: »).
+var colonParam = regexp.MustCompile(`(^|[^:]):([A-Za-z_][A-Za-z0-9_]*)`)
+
+// namedParams adapte les paramètres « :nom » des requêtes SQL du YAML : SQLite les accepte
+// directement avec sql.Named, les autres pilotes passent par la syntaxe « @nom » de GORM.
+func namedParams(db *gorm.DB, sqlText string) string {
+	if dialect(db) == "sqlite" {
+		return sqlText
+	}
+	return colonParam.ReplaceAllString(sqlText, "$1@$2")
+}
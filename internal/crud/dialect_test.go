@@ -0,0 +1,220 @@
+// internal/crud/dialect_test.go
+package crud
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// Les tests de dialecte tournent toujours sous SQLite (base en mémoire) ; PostgreSQL et
+// MySQL ne sont testés que si leur DSN est fourni :
+//
+//	GOCRUD_TEST_POSTGRES_DSN="host=localhost user=gocrud password=gocrud dbname=gocrud_test sslmode=disable"
+//	GOCRUD_TEST_MYSQL_DSN="gocrud:gocrud@tcp(localhost:3306)/gocrud_test?parseTime=true"
+
+// openDialect ouvre la base de test d'un pilote, ou ignore le test si son DSN n'est pas fourni.
+func openDialect(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	var dialector gorm.Dialector
+	switch name {
+	case "sqlite":
+		dialector = sqlite.Open("file::memory:")
+	case "postgres", "mysql":
+		env := "GOCRUD_TEST_" + strings.ToUpper(name) + "_DSN"
+		dsn := os.Getenv(env)
+		if dsn == "" {
+			t.Skipf("%s non défini", env)
+		}
+		if name == "postgres" {
+			dialector = postgres.Open(dsn)
+		} else {
+			dialector = mysql.Open(dsn)
+		}
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("%s : ouverture : %v", name, err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name == "sqlite" {
+		sqlDB.SetMaxOpenConns(1) // Une seule connexion : une seule base en mémoire
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// createDialectTables crée les tables de test : clé générée (dialect_auto) et clé saisie
+// (dialect_code), supprimées en fin de test.
+func createDialectTables(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	auto := "id INTEGER PRIMARY KEY AUTOINCREMENT"
+	switch dialect(db) {
+	case "postgres":
+		auto = "id SERIAL PRIMARY KEY"
+	case "mysql":
+		auto = "id INTEGER PRIMARY KEY AUTO_INCREMENT"
+	}
+	drop := func() {
+		db.Exec("DROP TABLE IF EXISTS dialect_auto")
+		db.Exec("DROP TABLE IF EXISTS dialect_code")
+	}
+	drop()
+	for _, ddl := range []string{
+		"CREATE TABLE dialect_auto (" + auto + ", label VARCHAR(50), n INTEGER NULL)",
+		"CREATE TABLE dialect_code (code VARCHAR(10), num INTEGER, label VARCHAR(50), PRIMARY KEY (code, num))",
+	} {
+		if err := db.Exec(ddl).Error; err != nil {
+			t.Fatalf("%s : %v", ddl, err)
+		}
+	}
+	t.Cleanup(drop)
+}
+
+func TestDialects(t *testing.T) {
+	for _, name := range []string{"sqlite", "postgres", "mysql"} {
+		t.Run(name, func(t *testing.T) {
+			db := openDialect(t, name)
+			createDialectTables(t, db)
+
+			// insertRow : clé générée relue par RETURNING ou LastInsertId
+			for i, label := range []string{"Alpha", "beta", "Gamma"} {
+				parts, err := insertRow(db, "dialect_auto", []string{"id"}, map[string]interface{}{"label": label})
+				if err != nil {
+					t.Fatalf("insertRow(%s) : %v", label, err)
+				}
+				if want := []string{string(rune('1' + i))}; len(parts) != 1 || parts[0] != want[0] {
+					t.Errorf("insertRow(%s) = %v, attendu %v", label, parts, want)
+				}
+			}
+			// insertRow : clé composée saisie, renvoyée telle quelle
+			parts, err := insertRow(db, "dialect_code", []string{"code", "num"}, map[string]interface{}{"code": "A", "num": 7, "label": "x"})
+			if err != nil || len(parts) != 2 || parts[0] != "A" || parts[1] != "7" {
+				t.Errorf("insertRow clé composée = %v, %v", parts, err)
+			}
+			db.Exec("UPDATE dialect_auto SET n = 5 WHERE id = 1")
+			db.Exec("UPDATE dialect_auto SET n = 1 WHERE id = 3")
+
+			// likeCondition : insensible à la casse, y compris sur une colonne numérique
+			likes := []struct {
+				field   string
+				textual bool
+				pattern string
+				want    int64
+			}{
+				{"label", true, "%ALP%", 1},
+				{"label", true, "%a%", 3},
+				{"label", true, "%zeta%", 0},
+				{"id", false, "%2%", 1},
+			}
+			for _, tc := range likes {
+				var count int64
+				if err := db.Table("dialect_auto").Where(likeCondition(db, tc.field, tc.textual), tc.pattern).Count(&count).Error; err != nil {
+					t.Errorf("likeCondition(%s, %q) : %v", tc.field, tc.pattern, err)
+				} else if count != tc.want {
+					t.Errorf("likeCondition(%s, %q) = %d lignes, attendu %d", tc.field, tc.pattern, count, tc.want)
+				}
+			}
+
+			// nullsFirst : NULL est la plus petite valeur dans les deux sens
+			orders := []struct {
+				dir  string
+				want []int
+			}{
+				{"ASC", []int{2, 3, 1}},
+				{"DESC", []int{1, 3, 2}},
+			}
+			for _, tc := range orders {
+				var ids []int
+				if err := db.Table("dialect_auto").Order("n "+nullsFirst(db, tc.dir)).Pluck("id", &ids).Error; err != nil {
+					t.Errorf("nullsFirst(%s) : %v", tc.dir, err)
+				} else if len(ids) != 3 || ids[0] != tc.want[0] || ids[1] != tc.want[1] || ids[2] != tc.want[2] {
+					t.Errorf("nullsFirst(%s) = %v, attendu %v", tc.dir, ids, tc.want)
+				}
+			}
+
+			// namedParams : paramètres « :nom » d'une requête du YAML, passés par sql.Named
+			var labels []string
+			q := namedParams(db, "SELECT label FROM dialect_auto WHERE id >= :min AND label <> :skip ORDER BY id")
+			if err := db.Raw(q, sql.Named("min", 2), sql.Named("skip", "Gamma")).Scan(&labels).Error; err != nil {
+				t.Errorf("namedParams : %v", err)
+			} else if len(labels) != 1 || labels[0] != "beta" {
+				t.Errorf("namedParams = %v, attendu [beta]", labels)
+			}
+		})
+	}
+}
+
+func TestNamedParams(t *testing.T) {
+	tests := []struct {
+		dialect string
+		in      string
+		want    string
+	}{
+		{"sqlite", "SELECT * FROM t WHERE a = :a", "SELECT * FROM t WHERE a = :a"},
+		{"postgres", "SELECT * FROM t WHERE a = :a AND b = :b_2", "SELECT * FROM t WHERE a = @a AND b = @b_2"},
+		{"postgres", "SELECT x::text FROM t WHERE a = :a", "SELECT x::text FROM t WHERE a = @a"},
+		{"mysql", ":a", "@a"},
+	}
+	for _, tc := range tests {
+		db := &gorm.DB{Config: &gorm.Config{Dialector: namedDialector(tc.dialect)}}
+		if got := namedParams(db, tc.in); got != tc.want {
+			t.Errorf("namedParams(%s, %q) = %q, attendu %q", tc.dialect, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestDialectConditions(t *testing.T) {
+	tests := []struct {
+		dialect string
+		like    string // likeCondition("id", false)
+		likeTxt string // likeCondition("label", true)
+		asc     string
+		desc    string
+	}{
+		{"sqlite", "id LIKE ?", "label LIKE ?", "ASC", "DESC"},
+		{"mysql", "id LIKE ?", "label LIKE ?", "ASC", "DESC"},
+		{"postgres", "CAST(id AS TEXT) ILIKE ?", "label ILIKE ?", "ASC NULLS FIRST", "DESC NULLS LAST"},
+	}
+	for _, tc := range tests {
+		db := &gorm.DB{Config: &gorm.Config{Dialector: namedDialector(tc.dialect)}}
+		if got := likeCondition(db, "id", false); got != tc.like {
+			t.Errorf("%s : likeCondition(id) = %q, attendu %q", tc.dialect, got, tc.like)
+		}
+		if got := likeCondition(db, "label", true); got != tc.likeTxt {
+			t.Errorf("%s : likeCondition(label) = %q, attendu %q", tc.dialect, got, tc.likeTxt)
+		}
+		if got := nullsFirst(db, "ASC"); got != tc.asc {
+			t.Errorf("%s : nullsFirst(ASC) = %q, attendu %q", tc.dialect, got, tc.asc)
+		}
+		if got := nullsFirst(db, "DESC"); got != tc.desc {
+			t.Errorf("%s : nullsFirst(DESC) = %q, attendu %q", tc.dialect, got, tc.desc)
+		}
+	}
+}
+
+// namedDialector est un dialecte dont seul le nom sert, pour tester les fonctions qui
+// n'interrogent pas la base.
+type namedDialector string
+
+func (d namedDialector) Name() string { return string(d) }
+
+func (namedDialector) Initialize(*gorm.DB) error                             { return nil }
+func (namedDialector) Migrator(*gorm.DB) gorm.Migrator                       { return nil }
+func (namedDialector) DataTypeOf(*schema.Field) string                       { return "" }
+func (namedDialector) DefaultValueOf(*schema.Field) clause.Expression        { return nil }
+func (namedDialector) BindVarTo(clause.Writer, *gorm.Statement, interface{}) {}
+func (namedDialector) QuoteTo(clause.Writer, string)                         {}
+func (namedDialector) Explain(string, ...interface{}) string                 { return "" }
@@ -0,0 +1,35 @@
+// internal/crud/enums.go
+package crud
+
+import (
+	"fmt"
+
+	"example.com/go-crud/internal/entity"
+)
+
+// checkEnumValue vérifie qu'une valeur saisie fait partie des valeurs d'un champ enum et
+// renvoie le message d'erreur, ou "" si elle est autorisée (ou vide).
+func checkEnumValue(f entity.Field, raw string) string {
+	if f.Enum == nil || raw == "" || f.Enum.Option(raw) != nil {
+		return ""
+	}
+	return fmt.Sprintf("Valeur « %s » non autorisée", raw)
+}
+
+// enumOptions renvoie les valeurs d'un champ enum au format des options de filtre (Value / Label).
+func enumOptions(f entity.Field) []map[string]interface{} {
+	opts := make([]map[string]interface{}, len(f.Enum.Values))
+	for i, v := range f.Enum.Values {
+		opts[i] = map[string]interface{}{"Value": v.Value, "Label": v.Label}
+	}
+	return opts
+}
+
+// enumLabel renvoie le libellé de la valeur d'un champ enum (la valeur elle-même si elle
+// n'est pas déclarée).
+func enumLabel(f entity.Field, v interface{}) string {
+	if opt := f.Enum.Option(v); opt != nil {
+		return opt.Label
+	}
+	return entity.EnumKey(v)
+}
@@ -0,0 +1,206 @@
+// internal/crud/filters.go
+package crud
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/go-crud/internal/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// listFilter décrit un filtre de colonne de la liste et sa valeur courante.
+// Les paramètres d'URL sont f_<champ> (valeur), f_<champ>_op (opérateur texte),
+// f_<champ>_min et f_<champ>_max (bornes des intervalles).
+type listFilter struct {
+	Field   string
+	Label   string
+	Kind    string // "number", "date", "boolean", "string" ou "combo" (champs liés et enum)
+	Value   string
+	Op      string // Chaînes : "contains" (défaut), "prefix" ou "eq"
+	Min     string
+	Max     string
+	Options []map[string]interface{} // Combo : Value / Label
+	Money   *entity.MoneyConfig      // Bornes d'un champ money, comparées en valeurs stockées
+}
+
+// active indique si le filtre restreint la liste.
+func (f listFilter) active() bool {
+	return f.Value != "" || f.Min != "" || f.Max != ""
+}
+
+// parseFilters lit dans l'URL les valeurs des filtres déclarés dans list.filterFields.
+func (h *crudHandler) parseFilters(c *gin.Context) []listFilter {
+	filters := make([]listFilter, 0, len(h.ec.List.FilterFields))
+	for _, name := range h.ec.List.FilterFields {
+		field, ok := h.ec.FieldsByName[name]
+		if !ok {
+			continue
+		}
+		f := listFilter{
+			Field: name,
+			Label: fieldLabel(h.ec, name),
+			Value: strings.TrimSpace(c.Query("f_" + name)),
+			Op:    c.DefaultQuery("f_"+name+"_op", "contains"),
+			Min:   strings.TrimSpace(c.Query("f_" + name + "_min")),
+			Max:   strings.TrimSpace(c.Query("f_" + name + "_max")),
+		}
+		switch {
+		case field.Enum != nil:
+			f.Kind = "combo"
+			f.Options = enumOptions(field)
+		case h.comboOptionsSQL(name) != "":
+			f.Kind = "combo"
+			f.Options = h.filterOptions(name)
+		case field.Type == "uint" || field.Type == "int" || field.Type == "number":
+			f.Kind = "number"
+		case field.Money != nil:
+			f.Kind = "number"
+			f.Money = field.Money
+		case field.Type == "date" || field.Type == "datetime":
+			f.Kind = "date"
+		case field.Type == "boolean":
+			f.Kind = "boolean"
+		default:
+			f.Kind = "string"
+		}
+		filters = append(filters, f)
+	}
+	return filters
+}
+
+// applyFilters ajoute à la requête les conditions des filtres actifs, combinées par AND.
+func applyFilters(q *gorm.DB, filters []listFilter) *gorm.DB {
+	for _, f := range filters {
+		if !f.active() {
+			continue
+		}
+		col := f.Field
+		switch f.Kind {
+		case "combo":
+			q = q.Where(col+" = ?", f.Value)
+		case "boolean":
+			if f.Value == "1" {
+				q = q.Where(col+" = ?", true)
+			} else if f.Value == "0" {
+				q = q.Where("("+col+" = ? OR "+col+" IS NULL)", false)
+			}
+		case "number":
+			if f.Money != nil {
+				if v, err := parseMoney(f.Min, f.Money.Digits); err == nil {
+					q = q.Where(col+" >= ?", storeMoney(v, f.Money))
+				}
+				if v, err := parseMoney(f.Max, f.Money.Digits); err == nil {
+					q = q.Where(col+" <= ?", storeMoney(v, f.Money))
+				}
+				continue
+			}
+			if v, err := parseFrenchNumber(f.Min); err == nil {
+				q = q.Where(col+" >= ?", v)
+			}
+			if v, err := parseFrenchNumber(f.Max); err == nil {
+				q = q.Where(col+" <= ?", v)
+			}
+		case "date":
+			// Les dates sont stockées au format ISO : la comparaison de chaînes suffit.
+			if t, err := time.Parse("2006-01-02", f.Min); err == nil {
+				q = q.Where(col+" >= ?", t.Format("2006-01-02"))
+			}
+			if t, err := time.Parse("2006-01-02", f.Max); err == nil {
+				q = q.Where(col+" < ?", t.AddDate(0, 0, 1).Format("2006-01-02"))
+			}
+		default:
+			switch f.Op {
+			case "eq":
+				q = q.Where(col+" = ?", f.Value)
+			case "prefix":
+				q = q.Where(likeCondition(q, col, true), f.Value+"%")
+			default:
+				q = q.Where(likeCondition(q, col, true), "%"+f.Value+"%")
+			}
+		}
+	}
+	return q
+}
+
+// filterQuery encode les filtres actifs en paramètres d'URL, pour les liens de pagination et de tri.
+func filterQuery(filters []listFilter) string {
+	v := url.Values{}
+	for _, f := range filters {
+		if f.Value != "" {
+			v.Set("f_"+f.Field, f.Value)
+			if f.Kind == "string" && f.Op != "contains" {
+				v.Set("f_"+f.Field+"_op", f.Op)
+			}
+		}
+		if f.Min != "" {
+			v.Set("f_"+f.Field+"_min", f.Min)
+		}
+		if f.Max != "" {
+			v.Set("f_"+f.Field+"_max", f.Max)
+		}
+	}
+	return v.Encode()
+}
+
+// comboOptionsSQL renvoie la requête fournissant les valeurs possibles d'un champ lié :
+// celle du combo_base de la fiche, sinon celle déduite de sa déclaration `references`.
+func (h *crudHandler) comboOptionsSQL(name string) string {
+	if fd, ok := h.ec.FicheFieldsByName[name]; ok && fd.ComboConfig != nil {
+		return fd.ComboConfig.SQL
+	}
+	if f, ok := h.ec.FieldsByName[name]; ok && f.References != nil {
+		ref := f.References
+		label := ref.Field
+		if ref.Display != "" {
+			label = ref.Display
+		}
+		return fmt.Sprintf("SELECT DISTINCT %s AS value, %s AS label FROM %s ORDER BY %s", ref.Field, label, ref.Table, label)
+	}
+	return ""
+}
+
+// optionsDB renvoie la base de la requête de comboOptionsSQL : source du combo_base,
+// sinon celle de la table référencée.
+func (h *crudHandler) optionsDB(name string) *gorm.DB {
+	if fd, ok := h.ec.FicheFieldsByName[name]; ok && fd.ComboConfig != nil {
+		return h.source(fd.ComboConfig.DataSource)
+	}
+	if f, ok := h.ec.FieldsByName[name]; ok && f.References != nil {
+		return h.refDB(f.References.Table)
+	}
+	return h.db
+}
+
+// filterOptions exécute la requête de comboOptionsSQL et renvoie les options Value / Label.
+func (h *crudHandler) filterOptions(name string) []map[string]interface{} {
+	var rows []map[string]interface{}
+	h.optionsDB(name).Raw(h.comboOptionsSQL(name)).Scan(&rows)
+
+	opts := make([]map[string]interface{}, 0, len(rows))
+	if fd, ok := h.ec.FicheFieldsByName[name]; ok && fd.ComboConfig != nil {
+		cc := fd.ComboConfig
+		for _, row := range rows {
+			var parts []string
+			for _, col := range cc.DisplayFields {
+				parts = append(parts, fmt.Sprint(row[col]))
+			}
+			opts = append(opts, map[string]interface{}{"Value": fmt.Sprint(row[cc.KeyField]), "Label": strings.Join(parts, cc.Separator)})
+		}
+		return opts
+	}
+	for _, row := range rows {
+		opts = append(opts, map[string]interface{}{"Value": fmt.Sprint(row["value"]), "Label": fmt.Sprint(row["label"])})
+	}
+	return opts
+}
+
+// parseFrenchNumber lit un nombre saisi à la française ("1 846,67", "-12,5").
+func parseFrenchNumber(s string) (float64, error) {
+	s = strings.NewReplacer(" ", "", " ", "", ",", ".").Replace(strings.TrimSpace(s))
+	return strconv.ParseFloat(s, 64)
+}
@@ -0,0 +1,159 @@
+// internal/crud/fulltext.go
+package crud
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"unicode"
+
+	"example.com/go-crud/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ftsTable renvoie le nom de la table virtuelle FTS5 d'une entité.
+func ftsTable(ec *entity.EntityConfig) string {
+	return ec.Table + "_fts"
+}
+
+// setupFullText crée (ou recrée si les champs indexés ont changé) l'index FTS5 de l'entité,
+// ainsi que les déclencheurs SQL qui le tiennent à jour à chaque insertion, modification et
+// suppression. La tokenisation unicode61 avec remove_diacritics rend la recherche insensible
+// aux accents. Renvoie false si l'index n'est pas disponible : la recherche reste alors en LIKE.
+// Le module FTS5 du pilote SQLite n'est compilé qu'avec le tag `sqlite_fts5`
+// (go build -tags sqlite_fts5).
+func setupFullText(db *gorm.DB, ec *entity.EntityConfig) bool {
+	if ec.FullText == nil {
+		return false
+	}
+	if db.Dialector.Name() != "sqlite" {
+		log.Printf("[FTS] Index plein texte de %s ignoré : SQLite uniquement", ec.Name)
+		return false
+	}
+	// Le rowid de l'index est la clé primaire : une seule colonne entière
+	if !ec.AutoKey() {
+		log.Printf("[FTS] Index plein texte de %s ignoré : clé primaire non entière ou composite", ec.Name)
+		return false
+	}
+	fts := ftsTable(ec)
+	key := ec.PrimaryKey[0]
+	fields := ec.FullText.Fields
+
+	// Sans module FTS5, les déclencheurs d'une exécution précédente bloqueraient les écritures
+	var enabled int
+	db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled)
+	if enabled == 0 {
+		for _, suffix := range []string{"_ai", "_ad", "_au"} {
+			db.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS %s%s", fts, suffix))
+		}
+		log.Printf("[FTS] ATTENTION : %s déclare fullText mais le module FTS5 est absent de ce binaire "+
+			"(compiler avec go build -tags sqlite_fts5) ; recherche en LIKE, sans index", ec.Name)
+		return false
+	}
+
+	// Index existant avec d'autres colonnes : on le supprime pour le reconstruire
+	var existing []string
+	if rows, err := db.Raw("SELECT name FROM pragma_table_info(?)", fts).Rows(); err == nil {
+		for rows.Next() {
+			var name string
+			rows.Scan(&name)
+			existing = append(existing, name)
+		}
+		rows.Close()
+	}
+	// Index à jour seulement si ses trois déclencheurs existent encore (ils sont supprimés
+	// lors d'une exécution sans FTS5 : l'index doit alors être reconstruit)
+	var triggers int64
+	db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN (?, ?, ?)",
+		fts+"_ai", fts+"_ad", fts+"_au").Scan(&triggers)
+	if len(existing) > 0 && slices.Equal(existing, fields) && triggers == 3 {
+		return true
+	}
+
+	cols := strings.Join(fields, ", ")
+	newCols := "new." + strings.Join(fields, ", new.")
+	oldCols := "old." + strings.Join(fields, ", old.")
+	stmts := []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_ai", fts),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_ad", fts),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_au", fts),
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", fts),
+		fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts5(%s, content='%s', content_rowid='%s', tokenize='unicode61 remove_diacritics 2')",
+			fts, cols, ec.Table, key),
+		fmt.Sprintf("CREATE TRIGGER %s_ai AFTER INSERT ON %s BEGIN INSERT INTO %s(rowid, %s) VALUES (new.%s, %s); END",
+			fts, ec.Table, fts, cols, key, newCols),
+		fmt.Sprintf("CREATE TRIGGER %s_ad AFTER DELETE ON %s BEGIN INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.%s, %s); END",
+			fts, ec.Table, fts, fts, cols, key, oldCols),
+		fmt.Sprintf("CREATE TRIGGER %s_au AFTER UPDATE ON %s BEGIN "+
+			"INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.%s, %s); "+
+			"INSERT INTO %s(rowid, %s) VALUES (new.%s, %s); END",
+			fts, ec.Table, fts, fts, cols, key, oldCols, fts, cols, key, newCols),
+		// Indexation des lignes déjà présentes
+		fmt.Sprintf("INSERT INTO %s(%s) VALUES ('rebuild')", fts, fts),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range stmts {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[FTS] Index plein texte de %s indisponible, recherche en LIKE : %v", ec.Name, err)
+		return false
+	}
+	log.Printf("[FTS] Index plein texte de %s construit sur %s", ec.Name, cols)
+	return true
+}
+
+// ftsMatch traduit une saisie utilisateur en requête FTS5 : chaque mot devient un préfixe
+// ("epar" trouve "Épargne") et tous les mots doivent être présents.
+func ftsMatch(search string) string {
+	words := strings.FieldsFunc(search, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, len(words))
+	for i, w := range words {
+		terms[i] = `"` + w + `"*`
+	}
+	return strings.Join(terms, " ")
+}
+
+// searchCondition renvoie la condition de recherche de la liste : correspondance dans l'index
+// plein texte s'il est actif, sinon LIKE sur chacun des list.searchableFields.
+func (h *crudHandler) searchCondition(search string) (string, []interface{}, bool) {
+	if h.fts {
+		match := ftsMatch(search)
+		if match == "" {
+			return "", nil, false
+		}
+		fts := ftsTable(h.ec)
+		return fmt.Sprintf("%s.%s IN (SELECT rowid FROM %s WHERE %s MATCH ?)", h.ec.Table, h.ec.PrimaryKey[0], fts, fts), []interface{}{match}, true
+	}
+	if len(h.ec.List.SearchableFields) == 0 {
+		return "", nil, false
+	}
+	var conds []string
+	var args []interface{}
+	for _, f := range h.ec.List.SearchableFields {
+		conds = append(conds, likeCondition(h.db, f, textualType(h.ec.FieldsByName[f].Type)))
+		args = append(args, "%"+search+"%")
+	}
+	return strings.Join(conds, " OR "), args, true
+}
+
+// searchRank renvoie le tri par pertinence (bm25) de la recherche plein texte.
+func (h *crudHandler) searchRank(search string) (clause.OrderBy, bool) {
+	match := ftsMatch(search)
+	if !h.fts || match == "" {
+		return clause.OrderBy{}, false
+	}
+	fts := ftsTable(h.ec)
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:  fmt.Sprintf("(SELECT rank FROM %s WHERE %s MATCH ? AND rowid = %s.%s)", fts, fts, h.ec.Table, h.ec.PrimaryKey[0]),
+		Vars: []interface{}{match},
+	}}, true
+}
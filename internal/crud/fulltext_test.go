@@ -0,0 +1,94 @@
+// internal/crud/fulltext_test.go
+package crud
+
+import (
+	"reflect"
+	"testing"
+
+	"example.com/go-crud/internal/entity"
+)
+
+// La saisie ne doit jamais être interprétée comme syntaxe FTS5 : opérateurs, guillemets,
+// filtres de colonne et parenthèses sont retirés, chaque mot devient un préfixe entre guillemets.
+var ftsMatchTests = []struct {
+	search string
+	want   string
+}{
+	{"", ""},
+	{"   ", ""},
+	{"epar", `"epar"*`},
+	{"Épargne  logement", `"Épargne"* "logement"*`},
+	{"livret OR compte", `"livret"* "OR"* "compte"*`},
+	{"NOT livret", `"NOT"* "livret"*`},
+	{"NEAR(livret compte)", `"NEAR"* "livret"* "compte"*`},
+	{`"livret`, `"livret"*`},
+	{`a"b`, `"a"* "b"*`},
+	{"titre:livret", `"titre"* "livret"*`},
+	{"-livret +compte*", `"livret"* "compte"*`},
+	{"^début", `"début"*`},
+	{"2024-01", `"2024"* "01"*`},
+	{`" * ( ) :`, ""},
+}
+
+func TestFtsMatch(t *testing.T) {
+	for _, tc := range ftsMatchTests {
+		if got := ftsMatch(tc.search); got != tc.want {
+			t.Errorf("ftsMatch(%q) = %q, attendu %q", tc.search, got, tc.want)
+		}
+	}
+}
+
+// Les requêtes produites sont acceptées par FTS5 et trouvent les mots par préfixe, sans
+// tenir compte des accents (go test -tags sqlite_fts5).
+func TestFtsMatchQuery(t *testing.T) {
+	db := openDialect(t, "sqlite")
+	var enabled int
+	db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled)
+	if enabled == 0 {
+		t.Skip("module FTS5 absent (go test -tags sqlite_fts5)")
+	}
+	db.Exec("CREATE TABLE doc (id INTEGER PRIMARY KEY, titre TEXT)")
+	db.Exec(`INSERT INTO doc (id, titre) VALUES (1, 'Livret A'), (2, 'Compte épargne'), (3, 'Plan d''épargne logement'), (4, 'Titre : "OR" NOT')`)
+	ec := &entity.EntityConfig{
+		Name:         "doc",
+		Table:        "doc",
+		PrimaryKey:   []string{"id"},
+		FieldsByName: map[string]entity.Field{"id": {Name: "id", Type: "int"}, "titre": {Name: "titre", Type: "string"}},
+		FullText:     &entity.FullTextConfig{Fields: []string{"titre"}},
+	}
+	if !setupFullText(db, ec) {
+		t.Fatal("index plein texte non créé")
+	}
+
+	tests := []struct {
+		search string
+		want   []int64
+	}{
+		{"epar", []int64{2, 3}},
+		{"EPARGNE log", []int64{3}},
+		{"livret OR compte", nil}, // OR est un mot à trouver, pas un opérateur
+		{"or not", []int64{4}},
+		{`titre:"livret`, nil},
+		{"NEAR(plan logement)", nil},
+		{"-compte", []int64{2}},
+	}
+	for _, tc := range tests {
+		var got []int64
+		if err := db.Raw("SELECT rowid FROM doc_fts WHERE doc_fts MATCH ? ORDER BY rowid", ftsMatch(tc.search)).Scan(&got).Error; err != nil {
+			t.Errorf("recherche %q : %v", tc.search, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("recherche %q : %v, attendu %v", tc.search, got, tc.want)
+		}
+	}
+	// Toutes les saisies non vides du tableau précédent sont aussi des requêtes valides
+	for _, tc := range ftsMatchTests {
+		if tc.want == "" {
+			continue
+		}
+		if err := db.Exec("SELECT rowid FROM doc_fts WHERE doc_fts MATCH ?", ftsMatch(tc.search)).Error; err != nil {
+			t.Errorf("recherche %q : %v", tc.search, err)
+		}
+	}
+}
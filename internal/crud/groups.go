@@ -0,0 +1,202 @@
+// internal/crud/groups.go
+package crud
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// listGroup est l'en-tête d'un groupe de lignes de la liste. Effectif et sous-totaux
+// portent sur toutes les lignes filtrées du groupe, pas seulement sur celles de la page.
+type listGroup struct {
+	Index     int
+	Label     string
+	Count     int64
+	Subtotals map[string]string // Colonne numérique affichée -> somme formatée
+	Continued bool              // Le groupe a commencé sur une page précédente
+}
+
+// groupField renvoie le champ de regroupement : paramètre "group" s'il est présent (vide pour
+// aucun regroupement), sinon list.groupBy. Seuls les champs proposés sont acceptés.
+func (h *crudHandler) groupField(c *gin.Context) string {
+	field, ok := c.GetQuery("group")
+	if !ok {
+		field = h.ec.List.GroupBy
+	}
+	if field == "" || (field != h.ec.List.GroupBy && !slices.Contains(h.ec.List.GroupFields, field)) {
+		return ""
+	}
+	if _, ok := h.ec.FieldsByName[field]; !ok {
+		return ""
+	}
+	return field
+}
+
+// groupOptions renvoie les champs proposés au regroupement, avec leur libellé.
+func (h *crudHandler) groupOptions() []map[string]string {
+	var opts []map[string]string
+	fields := h.ec.List.GroupFields
+	if h.ec.List.GroupBy != "" && !slices.Contains(fields, h.ec.List.GroupBy) {
+		fields = append([]string{h.ec.List.GroupBy}, fields...)
+	}
+	for _, name := range fields {
+		if _, ok := h.ec.FieldsByName[name]; ok {
+			opts = append(opts, map[string]string{"Value": name, "Label": fieldLabel(h.ec, name)})
+		}
+	}
+	return opts
+}
+
+// markGroups pose sur la première ligne de chaque groupe de la page un en-tête "_group",
+// et sur chaque ligne son numéro de groupe "_group_index". Les lignes doivent être triées
+// par le champ de regroupement. countQ est la requête filtrée, sans tri ni pagination ;
+// previous est la valeur du groupe de la dernière ligne de la page précédente.
+func (h *crudHandler) markGroups(countQ *gorm.DB, field string, columns []string, data []map[string]interface{}, previous interface{}, hasPrevious bool) {
+	if len(data) == 0 {
+		return
+	}
+	var numeric []string
+	for _, col := range columns {
+		if f, ok := h.ec.FieldsByName[col]; ok && (f.Type == "number" || f.Money != nil) && col != field {
+			numeric = append(numeric, col)
+		}
+	}
+
+	// Effectifs et sommes de tous les groupes présents sur la page, en une requête
+	var keys []interface{}
+	hasNull := false
+	for _, row := range data {
+		if row["_group_key"] == nil {
+			hasNull = true
+		} else if !slices.Contains(keys, row["_group_key"]) {
+			keys = append(keys, row["_group_key"])
+		}
+	}
+	sel := field + " AS grp, COUNT(*) AS n"
+	for i, col := range numeric {
+		expr := col
+		if m := h.ec.FieldsByName[col].Money; m != nil {
+			expr = moneyColumn(col, m) // Somme exacte en unités mineures
+		}
+		sel += fmt.Sprintf(", SUM(%s) AS s%d", expr, i)
+	}
+	q := countQ.Session(&gorm.Session{}).Select(sel).Group(field)
+	switch {
+	case len(keys) > 0 && hasNull:
+		q = q.Where(field+" IN ? OR "+field+" IS NULL", keys)
+	case len(keys) > 0:
+		q = q.Where(field+" IN ?", keys)
+	default:
+		q = q.Where(field + " IS NULL")
+	}
+	stats := make(map[string][]interface{})
+	rows, err := q.Rows()
+	if err != nil {
+		log.Printf("[GROUP] Erreur SQL pour %s : %v", h.ec.List.Name, err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		vals := make([]interface{}, 2+len(numeric))
+		dest := make([]interface{}, len(vals))
+		for i := range vals {
+			dest[i] = &vals[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			log.Printf("[GROUP] Erreur de lecture pour %s : %v", h.ec.List.Name, err)
+			return
+		}
+		stats[groupKey(vals[0])] = vals[1:]
+	}
+
+	labels := h.groupLabels(field)
+	index := 0
+	for i, row := range data {
+		key := groupKey(row["_group_key"])
+		if i == 0 || key != groupKey(data[i-1]["_group_key"]) {
+			index++
+			g := &listGroup{
+				Index:     index,
+				Label:     h.groupLabel(field, row["_group_key"], labels),
+				Subtotals: make(map[string]string),
+				Continued: i == 0 && hasPrevious && groupKey(previous) == key,
+			}
+			if st, ok := stats[key]; ok {
+				g.Count, _ = strconv.ParseInt(groupKey(st[0]), 10, 64)
+				for j, col := range numeric {
+					if st[j+1] == nil {
+						continue
+					}
+					if m := h.ec.FieldsByName[col].Money; m != nil {
+						if minor, ok := roundMinor(st[j+1]); ok {
+							g.Subtotals[col] = formatMoney(minor, m)
+						}
+						continue
+					}
+					if num, err := strconv.ParseFloat(groupKey(st[j+1]), 64); err == nil {
+						g.Subtotals[col] = h.formatListNumber(col, num)
+					}
+				}
+			}
+			row["_group"] = g
+		}
+		row["_group_index"] = index
+	}
+}
+
+// groupKey normalise une valeur de regroupement pour la comparaison.
+func groupKey(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// groupLabels charge les libellés des valeurs d'un champ lié (combo ou références).
+func (h *crudHandler) groupLabels(field string) map[string]string {
+	if h.comboOptionsSQL(field) == "" {
+		return nil
+	}
+	labels := make(map[string]string)
+	for _, opt := range h.filterOptions(field) {
+		labels[fmt.Sprint(opt["Value"])] = fmt.Sprint(opt["Label"])
+	}
+	return labels
+}
+
+// groupLabel renvoie le libellé affiché dans l'en-tête d'un groupe.
+func (h *crudHandler) groupLabel(field string, v interface{}, labels map[string]string) string {
+	key := groupKey(v)
+	if v == nil || key == "" {
+		return "(non renseigné)"
+	}
+	if label, ok := labels[key]; ok {
+		return label
+	}
+	if f := h.ec.FieldsByName[field]; f.Enum != nil {
+		return enumLabel(f, v)
+	}
+	if h.ec.FieldsByName[field].Type == "boolean" {
+		if key == "1" || key == "true" {
+			return "Oui"
+		}
+		return "Non"
+	}
+	return key
+}
+
+// formatListNumber formate un nombre avec les décimales et séparateurs du champ de la fiche.
+func (h *crudHandler) formatListNumber(field string, num float64) string {
+	if fd, ok := h.ec.FicheFieldsByName[field]; ok && fd.DecimalSeparator != "" {
+		return formatNumber(num, fd.Decimals, fd.DecimalSeparator, fd.ThousandsSeparator)
+	}
+	return strconv.FormatFloat(num, 'f', -1, 64)
+}
@@ -0,0 +1,392 @@
+// internal/crud/history.go
+package crud
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"example.com/go-crud/internal/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RecordVersion est une version complète d'un enregistrement (entités avec history: true),
+// écrite dans la transaction de la création ou de la modification.
+type RecordVersion struct {
+	ID        uint   `gorm:"primaryKey"`
+	Entity    string `gorm:"index:idx_version_record;size:100"`
+	RecordID  string `gorm:"index:idx_version_record;size:100"`
+	Version   int
+	Action    string `gorm:"size:10"` // "initial" (état avant le premier suivi), "create" ou "update"
+	Owner     string `gorm:"size:100"`
+	Data      string // JSON : enregistrement complet après l'opération
+	CreatedAt time.Time
+}
+
+// writeVersion enregistre l'état courant de l'enregistrement id comme nouvelle version.
+// previous est l'état avant modification : il devient la version 1 d'un enregistrement
+// créé avant l'activation de l'historique.
+func writeVersion(tx *gorm.DB, ec *entity.EntityConfig, id, action, user string, previous map[string]interface{}) error {
+	if !ec.History {
+		return nil
+	}
+	var last RecordVersion
+	if err := tx.Where("entity = ? AND record_id = ?", ec.Name, id).Order("version DESC").Limit(1).Find(&last).Error; err != nil {
+		return err
+	}
+	if last.ID == 0 && previous != nil {
+		if err := createVersion(tx, ec, id, 1, "initial", "", previous); err != nil {
+			return err
+		}
+		last.Version = 1
+	}
+	q, err := whereKey(tx.Table(ec.Table), ec, id)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]interface{})
+	if err := q.Select("*").Take(&current).Error; err != nil {
+		return err
+	}
+	return createVersion(tx, ec, id, last.Version+1, action, user, current)
+}
+
+// createVersion écrit la version n de l'enregistrement id.
+func createVersion(tx *gorm.DB, ec *entity.EntityConfig, id string, version int, action, user string, row map[string]interface{}) error {
+	b, err := json.Marshal(auditValues(row))
+	if err != nil {
+		return err
+	}
+	return tx.Create(&RecordVersion{
+		Entity:   ec.Name,
+		RecordID: id,
+		Version:  version,
+		Action:   action,
+		Owner:    user,
+		Data:     string(b),
+	}).Error
+}
+
+// versions renvoie les versions de l'enregistrement id, de la plus récente à la plus ancienne.
+func (h *crudHandler) versions(id string) ([]RecordVersion, error) {
+	var list []RecordVersion
+	err := h.db.Where("entity = ? AND record_id = ?", h.ec.Name, id).Order("version DESC").Find(&list).Error
+	return list, err
+}
+
+// snapshot relit les valeurs d'une version : nombres exacts, dates et heures en time.Time.
+func (h *crudHandler) snapshot(v RecordVersion) map[string]interface{} {
+	row := make(map[string]interface{})
+	dec := json.NewDecoder(bytes.NewReader([]byte(v.Data)))
+	dec.UseNumber()
+	dec.Decode(&row)
+	for _, f := range h.ec.Fields {
+		s, ok := row[f.Name].(string)
+		if !ok || (f.Type != "date" && f.Type != "datetime") {
+			continue
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				row[f.Name] = t
+				break
+			}
+		}
+	}
+	return row
+}
+
+// displayValue formate une valeur de version pour l'affichage (libellés, dates, nombres).
+func (h *crudHandler) displayValue(f entity.Field, v interface{}) string {
+	if f.Type == "boolean" { // NULL et faux s'affichent de la même façon
+		if s := fmt.Sprint(v); s == "true" || s == "1" {
+			return "Oui"
+		}
+		return "Non"
+	}
+	if v == nil {
+		return ""
+	}
+	switch f.Type {
+	case "enum":
+		return enumLabel(f, v)
+	case "money":
+		return fmt.Sprint(displayMoney(v, f.Money))
+	case "date", "datetime":
+		if t, ok := v.(time.Time); ok {
+			layout := f.DisplayFormat
+			if layout == "" {
+				layout = "02/01/2006"
+				if f.Type == "datetime" {
+					layout = "02/01/2006 15:04"
+				}
+			}
+			return t.Format(layout)
+		}
+	case "number":
+		if num, err := strconv.ParseFloat(fmt.Sprint(v), 64); err == nil {
+			return h.formatListNumber(f.Name, num)
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// historyField est une ligne de la version affichée ou de la comparaison de deux versions.
+type historyField struct {
+	Label   string
+	Before  string // Comparaison uniquement
+	Value   string
+	Changed bool
+}
+
+// historyEntry est une version de la chronologie, avec les libellés des champs modifiés.
+type historyEntry struct {
+	Version int
+	Action  string
+	Owner   string
+	Date    string
+	Changed []string
+}
+
+// historyTimelineSize est le nombre de versions récentes affichées dans la fiche.
+const historyTimelineSize = 10
+
+// timeline renvoie la chronologie des versions (champs modifiés par rapport à la version précédente).
+func (h *crudHandler) timeline(list []RecordVersion) []historyEntry {
+	entries := make([]historyEntry, len(list))
+	for i, v := range list {
+		e := historyEntry{Version: v.Version, Action: v.Action, Owner: v.Owner, Date: v.CreatedAt.Local().Format("02/01/2006 15:04:05")}
+		if i+1 < len(list) {
+			for _, row := range h.compareVersions(list[i+1], v) {
+				if row.Changed {
+					e.Changed = append(e.Changed, row.Label)
+				}
+			}
+		}
+		entries[i] = e
+	}
+	return entries
+}
+
+// recentHistory renvoie les dernières versions affichées dans la fiche (aucune en création).
+func (h *crudHandler) recentHistory(id string) []historyEntry {
+	if !h.ec.History || id == "" {
+		return nil
+	}
+	list, err := h.versions(id)
+	if err != nil {
+		log.Printf("[HISTORY] %s/%s : %v", h.ec.Name, id, err)
+	}
+	// Une version de plus pour les champs modifiés de la plus ancienne affichée
+	if len(list) > historyTimelineSize+1 {
+		list = list[:historyTimelineSize+1]
+	}
+	entries := h.timeline(list)
+	if len(entries) > historyTimelineSize {
+		entries = entries[:historyTimelineSize]
+	}
+	return entries
+}
+
+// compareVersions compare deux versions champ par champ, avec les libellés de l'entité.
+func (h *crudHandler) compareVersions(from, to RecordVersion) []historyField {
+	before, after := h.snapshot(from), h.snapshot(to)
+	rows := make([]historyField, 0, len(h.ec.Fields))
+	for _, f := range h.ec.Fields {
+		b, a := h.displayValue(f, before[f.Name]), h.displayValue(f, after[f.Name])
+		rows = append(rows, historyField{Label: fieldLabel(h.ec, f.Name), Before: b, Value: a, Changed: a != b})
+	}
+	return rows
+}
+
+// versionAt renvoie la version en vigueur à la date at (la dernière créée avant), faux si
+// l'enregistrement n'avait encore aucune version connue.
+func versionAt(list []RecordVersion, at time.Time) (RecordVersion, bool) {
+	for _, v := range list {
+		if !v.CreatedAt.After(at) {
+			return v, true
+		}
+	}
+	return RecordVersion{}, false
+}
+
+// findVersion renvoie la version numéro n.
+func findVersion(list []RecordVersion, n int) (RecordVersion, bool) {
+	for _, v := range list {
+		if v.Version == n {
+			return v, true
+		}
+	}
+	return RecordVersion{}, false
+}
+
+// history affiche l'historique d'un enregistrement : chronologie, version choisie (numéro
+// ?version= ou date ?at=) et comparaison de deux versions (?from= et ?to=).
+func (h *crudHandler) history(c *gin.Context) {
+	id := c.Param("id")
+	list, err := h.versions(id)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Erreur de lecture de l'historique : %v", err)
+		return
+	}
+	data := gin.H{
+		"Entity":   h.ec,
+		"Key":      id,
+		"Timeline": h.timeline(list),
+		"At":       c.Query("at"),
+		"Query":    template.URL(historyListQuery(c)),
+	}
+	params := make(map[string]string)
+	for k, v := range c.Request.URL.Query() {
+		if k != "version" && k != "at" && k != "from" && k != "to" {
+			params[k] = v[0]
+		}
+	}
+	data["ListParams"] = params
+
+	var shown RecordVersion
+	found := len(list) > 0
+	if found {
+		shown = list[0]
+	}
+	if at := c.Query("at"); at != "" {
+		t, err := parseHistoryDate(at)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Date invalide : %s", at)
+			return
+		}
+		shown, found = versionAt(list, t)
+		if !found {
+			data["Message"] = "Aucune version connue de cet enregistrement au " + t.Format("02/01/2006 15:04") + "."
+		}
+	} else if n, err := strconv.Atoi(c.Query("version")); err == nil {
+		shown, found = findVersion(list, n)
+		if !found {
+			c.String(http.StatusNotFound, "Version %d introuvable", n)
+			return
+		}
+	}
+	if found {
+		data["Shown"] = shown
+		var fields []historyField
+		row := h.snapshot(shown)
+		for _, f := range h.ec.Fields {
+			fields = append(fields, historyField{Label: fieldLabel(h.ec, f.Name), Value: h.displayValue(f, row[f.Name])})
+		}
+		data["Fields"] = fields
+	}
+
+	if c.Query("from") != "" && c.Query("to") != "" {
+		fromN, _ := strconv.Atoi(c.Query("from"))
+		toN, _ := strconv.Atoi(c.Query("to"))
+		from, ok1 := findVersion(list, fromN)
+		to, ok2 := findVersion(list, toN)
+		if !ok1 || !ok2 {
+			c.String(http.StatusNotFound, "Version introuvable")
+			return
+		}
+		data["From"], data["To"] = from, to
+		data["Diff"] = h.compareVersions(from, to)
+	}
+	c.HTML(http.StatusOK, "history.html", data)
+}
+
+// parseHistoryDate lit la date d'une consultation : jour (fin de journée) ou date et heure.
+func parseHistoryDate(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02T15:04", s, time.Local); err == nil {
+		return t.Add(time.Minute - time.Nanosecond), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return t, err
+	}
+	return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// revertVersion restaure une version : ses valeurs sont soumises comme une saisie de la fiche,
+// avec la même validation, les mêmes déclencheurs et contraintes qu'une modification.
+// En cas d'erreur, la fiche est ré-affichée avec les valeurs restaurées à corriger.
+func (h *crudHandler) revertVersion(c *gin.Context) {
+	list, err := h.versions(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Erreur de lecture de l'historique : %v", err)
+		return
+	}
+	n, _ := strconv.Atoi(c.Param("version"))
+	v, ok := findVersion(list, n)
+	if !ok {
+		c.String(http.StatusNotFound, "Version %d introuvable", n)
+		return
+	}
+	c.Request.PostForm = h.formValues(h.snapshot(v))
+	h.update(c)
+}
+
+// formValues traduit un enregistrement en valeurs de formulaire, telles que la fiche les poste.
+func (h *crudHandler) formValues(row map[string]interface{}) url.Values {
+	form := make(url.Values)
+	for _, grp := range h.ec.Fiche.Groups {
+		for _, fd := range grp.Fields {
+			f, ok := h.ec.FieldsByName[fd.Name]
+			v := row[fd.Name]
+			if ok && f.Attachment != nil { // Pièce jointe de la version, retrouvée par attachRecord
+				form.Set(fd.Name+"__restore", keyPart(v))
+				continue
+			}
+			if !ok || v == nil {
+				continue
+			}
+			if f.Enum != nil {
+				form.Set(fd.Name, entity.EnumKey(v))
+				continue
+			}
+			if f.Money != nil {
+				if minor, ok := moneyValue(v, f.Money); ok {
+					form.Set(fd.Name, moneyAmount(minor, f.Money))
+				}
+				continue
+			}
+			switch t := v.(type) {
+			case time.Time:
+				switch {
+				case f.Type == "date":
+					form.Set(fd.Name, t.Format("2006-01-02"))
+				case f.DisplayFormat != "":
+					form.Set(fd.Name, t.Format(f.DisplayFormat))
+				default:
+					form.Set(fd.Name, t.Format("2006-01-02 15:04:05"))
+				}
+			case bool:
+				if t {
+					form.Set(fd.Name, "on")
+				}
+			default:
+				s := fmt.Sprint(v)
+				if f.Type == "boolean" {
+					if s == "1" || s == "true" {
+						form.Set(fd.Name, "on")
+					}
+					continue
+				}
+				form.Set(fd.Name, s)
+			}
+		}
+	}
+	return form
+}
+
+// historyListQuery renvoie les paramètres de la liste (page, tri, recherche, filtres) à conserver
+// dans les liens de l'historique et de la fiche.
+func historyListQuery(c *gin.Context) string {
+	q := c.Request.URL.Query()
+	for _, k := range []string{"version", "at", "from", "to"} {
+		q.Del(k)
+	}
+	return q.Encode()
+}
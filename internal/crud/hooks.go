@@ -0,0 +1,110 @@
+// internal/crud/hooks.go
+package crud
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"example.com/go-crud/internal/entity"
+	"gorm.io/gorm"
+)
+
+// HookContext est transmis aux hooks d'enregistrement d'une entité.
+type HookContext struct {
+	Entity   *entity.EntityConfig
+	ID       string                 // Clé encodée de l'enregistrement (renseignée après la création)
+	Values   map[string]interface{} // Valeurs issues de bindAndConvertForm, modifiables par les hooks "Before"
+	Previous map[string]interface{} // Enregistrement avant modification ou suppression (nil en création)
+	User     string                 // Utilisateur courant ("" si inconnu)
+	Tx       *gorm.DB               // Transaction en cours : tout est annulé si un hook renvoie une erreur
+}
+
+// HookFunc est une fonction appelée autour de la persistance d'un enregistrement.
+// Renvoyer une erreur annule l'opération ; une FieldErrors est affichée champ par champ dans la fiche.
+type HookFunc func(hc *HookContext) error
+
+// Hooks regroupe les callbacks d'une entité. Les callbacks nil sont ignorés.
+type Hooks struct {
+	BeforeCreate HookFunc
+	AfterCreate  HookFunc
+	BeforeUpdate HookFunc
+	AfterUpdate  HookFunc
+	BeforeDelete HookFunc
+	AfterDelete  HookFunc
+}
+
+// FieldErrors permet à un hook de refuser un enregistrement avec un message par champ.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for f := range e {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	msgs := make([]string, 0, len(fields))
+	for _, f := range fields {
+		msgs = append(msgs, e[f])
+	}
+	return strings.Join(msgs, " ; ")
+}
+
+// hookEvent identifie le moment où un hook est appelé.
+type hookEvent int
+
+const (
+	beforeCreate hookEvent = iota
+	afterCreate
+	beforeUpdate
+	afterUpdate
+	beforeDelete
+	afterDelete
+)
+
+// hooksByEntity contient les hooks enregistrés, par nom d'entité.
+var hooksByEntity = make(map[string][]Hooks)
+
+// RegisterHooks ajoute des hooks pour l'entité nommée (champ `entity.name` du YAML).
+// Plusieurs appels pour la même entité cumulent les hooks, exécutés dans l'ordre d'enregistrement.
+// À appeler avant le démarrage du serveur.
+func RegisterHooks(entityName string, hooks Hooks) {
+	hooksByEntity[entityName] = append(hooksByEntity[entityName], hooks)
+}
+
+// runHooks exécute les hooks de l'entité pour l'évènement donné et s'arrête à la première erreur.
+func runHooks(event hookEvent, hc *HookContext) error {
+	for _, hooks := range hooksByEntity[hc.Entity.Name] {
+		var fn HookFunc
+		switch event {
+		case beforeCreate:
+			fn = hooks.BeforeCreate
+		case afterCreate:
+			fn = hooks.AfterCreate
+		case beforeUpdate:
+			fn = hooks.BeforeUpdate
+		case afterUpdate:
+			fn = hooks.AfterUpdate
+		case beforeDelete:
+			fn = hooks.BeforeDelete
+		case afterDelete:
+			fn = hooks.AfterDelete
+		}
+		if fn == nil {
+			continue
+		}
+		if err := fn(hc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// asFieldErrors indique si err (ou une erreur qu'elle enveloppe) est une FieldErrors.
+func asFieldErrors(err error) (FieldErrors, bool) {
+	var fe FieldErrors
+	if errors.As(err, &fe) {
+		return fe, true
+	}
+	return nil, false
+}
@@ -0,0 +1,187 @@
+// internal/crud/hooks_test.go
+package crud
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/go-crud/internal/entity"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+)
+
+// ficheCapture remplace le rendu des gabarits : elle retient le gabarit et les données
+// de la dernière page rendue.
+type ficheCapture struct {
+	name string
+	data gin.H
+}
+
+func (fc *ficheCapture) Instance(name string, data interface{}) render.Render {
+	fc.name = name
+	fc.data, _ = data.(gin.H)
+	return render.Data{ContentType: "text/html; charset=utf-8"}
+}
+
+// postForm soumet le formulaire au handler et renvoie le statut et la page rendue.
+func postForm(t *testing.T, handle gin.HandlerFunc, path, id string, form url.Values) (int, *ficheCapture) {
+	t.Helper()
+	c, r := gin.CreateTestContext(httptest.NewRecorder())
+	fc := &ficheCapture{}
+	r.HTMLRender = fc
+	c.Request = httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if id != "" {
+		c.Params = gin.Params{{Key: "id", Value: id}}
+	}
+	c.Set("user", "alice")
+	handle(c)
+	return c.Writer.Status(), fc
+}
+
+// hookHandler renvoie le handler d'une entité compte (clé générée, champ nom saisi) dont les
+// hooks sont retirés à la fin du test.
+func hookHandler(t *testing.T, name string) *crudHandler {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := openDialect(t, "sqlite")
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE TABLE " + name + " (id INTEGER PRIMARY KEY, nom TEXT)").Error; err != nil {
+		t.Fatal(err)
+	}
+	fields := []entity.Field{{Name: "id", Type: "int"}, {Name: "nom", Type: "string"}}
+	ec := &entity.EntityConfig{
+		Name:         name,
+		Table:        name,
+		PrimaryKey:   []string{"id"},
+		Fields:       fields,
+		FieldsByName: map[string]entity.Field{"id": fields[0], "nom": fields[1]},
+	}
+	ec.List.Name = name + "List"
+	ec.List.DefaultSortField = "id"
+	ec.Fiche.Name = name + "Fiche"
+	ec.Fiche.Groups = []entity.Group{{Name: "Général", Fields: []entity.FieldDef{{Name: "id"}, {Name: "nom"}}}}
+	t.Cleanup(func() { delete(hooksByEntity, name) })
+	return &crudHandler{db: db, ec: ec}
+}
+
+// names renvoie les noms enregistrés dans la table, par clé.
+func names(t *testing.T, h *crudHandler) []string {
+	t.Helper()
+	var got []string
+	if err := h.db.Table(h.ec.Table).Order("id").Pluck("nom", &got).Error; err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+// Les hooks "Before" modifient les valeurs enregistrées, dans l'ordre d'enregistrement.
+func TestHooksBeforeChangeValues(t *testing.T) {
+	h := hookHandler(t, "hook_before")
+	upper := func(hc *HookContext) error {
+		hc.Values["nom"] = strings.ToUpper(hc.Values["nom"].(string))
+		return nil
+	}
+	RegisterHooks("hook_before", Hooks{BeforeCreate: upper, BeforeUpdate: upper})
+	RegisterHooks("hook_before", Hooks{BeforeCreate: func(hc *HookContext) error {
+		hc.Values["nom"] = hc.Values["nom"].(string) + " (" + hc.User + ")"
+		return nil
+	}})
+
+	if status, _ := postForm(t, h.create, "/hook_beforeFiche/new", "", url.Values{"nom": {"dupont"}}); status != http.StatusSeeOther {
+		t.Fatalf("création : statut %d", status)
+	}
+	if status, _ := postForm(t, h.update, "/hook_beforeFiche/edit/1", "1", url.Values{"nom": {"durand"}}); status != http.StatusSeeOther {
+		t.Fatalf("modification : statut %d", status)
+	}
+	if status, _ := postForm(t, h.create, "/hook_beforeFiche/new", "", url.Values{"nom": {"martin"}}); status != http.StatusSeeOther {
+		t.Fatalf("création : statut %d", status)
+	}
+	if got, want := names(t, h), []string{"DURAND", "MARTIN (alice)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("noms enregistrés %q, attendu %q", got, want)
+	}
+}
+
+// Une FieldErrors renvoyée par un hook arrête l'enregistrement et s'affiche dans la fiche.
+func TestHooksFieldErrors(t *testing.T) {
+	h := hookHandler(t, "hook_errors")
+	h.db.Exec("INSERT INTO hook_errors (id, nom) VALUES (1, 'dupont')")
+	refuse := func(hc *HookContext) error {
+		if hc.Values["nom"] == "admin" {
+			return FieldErrors{"nom": "Nom réservé"}
+		}
+		return nil
+	}
+	RegisterHooks("hook_errors", Hooks{BeforeCreate: refuse, BeforeUpdate: refuse})
+
+	tests := []struct {
+		name   string
+		handle gin.HandlerFunc
+		id     string
+		mode   string
+	}{
+		{"création", h.create, "", "new"},
+		{"modification", h.update, "1", "edit"},
+	}
+	for _, tc := range tests {
+		status, fc := postForm(t, tc.handle, "/hook_errorsFiche", tc.id, url.Values{"nom": {"admin"}})
+		if status != http.StatusBadRequest || fc.name != "form.html" {
+			t.Errorf("%s : statut %d, gabarit %q ; attendu la fiche en 400", tc.name, status, fc.name)
+			continue
+		}
+		if want := map[string]string{"nom": "Nom réservé"}; !reflect.DeepEqual(fc.data["Errors"], want) || fc.data["Mode"] != tc.mode {
+			t.Errorf("%s : erreurs %v (mode %v), attendu %v", tc.name, fc.data["Errors"], fc.data["Mode"], want)
+		}
+		if row := fc.data["DataRow"].(map[string]interface{}); row["nom"] != "admin" {
+			t.Errorf("%s : saisie non conservée dans la fiche : %v", tc.name, row)
+		}
+	}
+	if got, want := names(t, h), []string{"dupont"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("noms enregistrés %q, attendu %q", got, want)
+	}
+}
+
+// Une erreur d'un hook "After" annule la transaction : ni l'enregistrement ni le journal ne sont écrits.
+func TestHooksAfterRollback(t *testing.T) {
+	h := hookHandler(t, "hook_after")
+	h.db.Exec("INSERT INTO hook_after (id, nom) VALUES (1, 'dupont')")
+	fail := func(hc *HookContext) error {
+		if hc.ID == "" {
+			t.Error("hook After appelé sans clé d'enregistrement")
+		}
+		return errors.New("service de notification indisponible")
+	}
+	RegisterHooks("hook_after", Hooks{AfterCreate: fail, AfterUpdate: fail})
+
+	tests := []struct {
+		name   string
+		handle gin.HandlerFunc
+		id     string
+		prefix string
+	}{
+		{"création", h.create, "", "Erreur de création"},
+		{"modification", h.update, "1", "Erreur de mise à jour"},
+	}
+	for _, tc := range tests {
+		status, fc := postForm(t, tc.handle, "/hook_afterFiche", tc.id, url.Values{"nom": {"durand"}})
+		want := map[string]string{formErrorKey: tc.prefix + " : service de notification indisponible"}
+		if status != http.StatusBadRequest || !reflect.DeepEqual(fc.data["Errors"], want) {
+			t.Errorf("%s : statut %d, erreurs %v ; attendu 400 et %v", tc.name, status, fc.data["Errors"], want)
+		}
+	}
+	if got, want := names(t, h), []string{"dupont"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("noms enregistrés %q, attendu %q", got, want)
+	}
+	var audits int64
+	h.db.Model(&AuditEntry{}).Count(&audits)
+	if audits != 0 {
+		t.Errorf("%d entrées de journal écrites malgré l'annulation", audits)
+	}
+}
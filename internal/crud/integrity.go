@@ -0,0 +1,270 @@
+// internal/crud/integrity.go
+package crud
+
+import (
+	"fmt"
+	"strings"
+
+	"example.com/go-crud/internal/entity"
+	"gorm.io/gorm"
+)
+
+// registered contient les configurations de toutes les entités enregistrées,
+// afin de retrouver les champs qui référencent une table lors d'une suppression.
+var registered []*entity.EntityConfig
+
+// checkConstraints vérifie les contraintes d'unicité et de référence sur les valeurs converties.
+// id (clé encodée) est vide en création ; en modification il exclut l'enregistrement courant des doublons.
+// Une requête en échec est renvoyée en erreur : la contrainte n'est jamais considérée comme respectée.
+func (h *crudHandler) checkConstraints(db *gorm.DB, vals map[string]interface{}, id string) (map[string]string, error) {
+	errors := make(map[string]string)
+
+	// Les champs absents du formulaire (readonly...) sont lus dans l'enregistrement existant.
+	var current map[string]interface{}
+	if id != "" {
+		current = make(map[string]interface{})
+		q, err := whereKey(db.Table(h.ec.Table), h.ec, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := q.Select("*").Take(&current).Error; err != nil {
+			return nil, err
+		}
+	}
+	valueOf := func(name string) interface{} {
+		if v, ok := vals[name]; ok {
+			return v
+		}
+		return current[name]
+	}
+
+	// Une clé saisie (non générée) est unique comme les contraintes déclarées
+	uniques := h.ec.Uniques
+	if id == "" && !h.ec.GeneratedKey() {
+		uniques = append([]entity.UniqueConfig{{Fields: h.ec.PrimaryKey}}, uniques...)
+	}
+	for _, u := range uniques {
+		if len(u.Fields) == 0 {
+			continue
+		}
+		q := db.Table(h.ec.Table)
+		var shown []string
+		skip := false
+		for _, name := range u.Fields {
+			v := valueOf(name)
+			if v == nil {
+				// NULL n'est jamais égal à NULL : pas de doublon possible.
+				skip = true
+				break
+			}
+			q = q.Where(name+" = ?", v)
+			shown = append(shown, fmt.Sprint(v))
+		}
+		if skip {
+			continue
+		}
+		if id != "" {
+			where, args, err := keyCondition(h.ec, "", id)
+			if err != nil {
+				return nil, err
+			}
+			q = q.Where("NOT ("+where+")", args...)
+		}
+		var count int64
+		if err := q.Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			continue
+		}
+		msg := u.Message
+		if msg == "" {
+			msg = fmt.Sprintf("La valeur « %s » existe déjà pour %s.", strings.Join(shown, " / "), h.fieldLabels(u.Fields))
+		}
+		errors[u.Fields[0]] = msg
+	}
+
+	for _, f := range h.ec.Fields {
+		if f.References == nil {
+			continue
+		}
+		v, ok := vals[f.Name]
+		if !ok || v == nil {
+			continue
+		}
+		// La table référencée peut appartenir à une autre source de données
+		refDB := db
+		if other := tableDB(f.References.Table); other != nil && other != h.db {
+			refDB = other
+		}
+		var count int64
+		if err := refDB.Table(f.References.Table).Where(f.References.Field+" = ?", v).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			continue
+		}
+		msg := f.References.Message
+		if msg == "" {
+			msg = fmt.Sprintf("La valeur « %v » n'existe pas dans %s.", v, f.References.Table)
+		}
+		errors[f.Name] = msg
+	}
+	return errors, nil
+}
+
+// fieldLabels renvoie les libellés des champs, séparés par des virgules.
+func (h *crudHandler) fieldLabels(names []string) string {
+	labels := make([]string, 0, len(names))
+	for _, name := range names {
+		labels = append(labels, fieldLabel(h.ec, name))
+	}
+	return strings.Join(labels, ", ")
+}
+
+// deleteWithReferences supprime un enregistrement en appliquant la règle onDelete
+// de chaque champ qui le référence : refus (restrict), suppression en cascade ou mise à NULL.
+// Les hooks BeforeDelete/AfterDelete sont appelés pour chaque ligne supprimée, cascade comprise.
+// visited évite les boucles sur les références circulaires ou auto-référencées.
+// Chaque ligne supprimée est inscrite au journal au nom de user ; id est la clé encodée.
+func deleteWithReferences(tx *gorm.DB, ec *entity.EntityConfig, id string, user string, visited map[string]bool) error {
+	if visited[ec.Table+":"+id] {
+		return nil
+	}
+	visited[ec.Table+":"+id] = true
+	if err := checkLock(tx, ec, id, user); err != nil {
+		return err
+	}
+
+	row, err := whereKey(tx.Table(ec.Table), ec, id)
+	if err != nil {
+		return err
+	}
+	previous := make(map[string]interface{})
+	if err := row.Session(&gorm.Session{}).Select("*").Take(&previous).Error; err != nil {
+		return err
+	}
+
+	for _, child := range registered {
+		for _, f := range child.Fields {
+			ref := f.References
+			if ref == nil || ref.Table != ec.Table {
+				continue
+			}
+
+			// Valeur référencée (l'id le plus souvent, mais pas obligatoirement).
+			refValue := previous[ref.Field]
+			if b, ok := refValue.([]byte); ok {
+				refValue = string(b)
+			}
+			if refValue == nil {
+				continue
+			}
+
+			// Lignes d'une autre source de données : hors de la transaction, elles ne peuvent
+			// être ni supprimées ni modifiées, seulement comptées pour refuser la suppression.
+			if other := tableDB(child.Table); other != nil && other != tableDB(ec.Table) {
+				var count int64
+				if err := other.Table(child.Table).Where(f.Name+" = ?", refValue).Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					return fmt.Errorf("Suppression impossible : %d enregistrement(s) de « %s » y font référence (champ « %s », autre source de données).",
+						count, child.LabelPlural, fieldLabel(child, f.Name))
+				}
+				continue
+			}
+
+			var childRows []map[string]interface{}
+			if err := tx.Table(child.Table).Select(child.PrimaryKey).Where(f.Name+" = ?", refValue).Find(&childRows).Error; err != nil {
+				return err
+			}
+			childIDs := make([]string, len(childRows))
+			for i, r := range childRows {
+				childIDs[i] = rowKey(child, r)
+			}
+			// Une ligne qui se référence elle-même n'empêche pas sa propre suppression.
+			childIDs = withoutID(childIDs, child.Table == ec.Table, id)
+			if len(childIDs) == 0 {
+				continue
+			}
+
+			switch ref.OnDelete {
+			case "cascade":
+				for _, childID := range childIDs {
+					if err := deleteWithReferences(tx, child, childID, user, visited); err != nil {
+						return err
+					}
+				}
+			case "nullify":
+				// État avant modification, pour l'historique des versions de l'enfant
+				before := make(map[string]map[string]interface{})
+				if child.History {
+					for _, childID := range childIDs {
+						q, _ := whereKey(tx.Table(child.Table), child, childID)
+						row := make(map[string]interface{})
+						if err := q.Select("*").Take(&row).Error; err != nil {
+							return err
+						}
+						before[childID] = row
+					}
+				}
+				if err := tx.Table(child.Table).Where(f.Name+" = ?", refValue).Update(f.Name, nil).Error; err != nil {
+					return err
+				}
+				for _, childID := range childIDs {
+					changes := map[string][2]interface{}{f.Name: {refValue, nil}}
+					if err := writeAudit(tx, child, childID, "update", user, changes); err != nil {
+						return err
+					}
+					if err := writeVersion(tx, child, childID, "update", user, before[childID]); err != nil {
+						return err
+					}
+				}
+			default:
+				return fmt.Errorf("Suppression impossible : %d enregistrement(s) de « %s » y font référence (champ « %s »).",
+					len(childIDs), child.LabelPlural, fieldLabel(child, f.Name))
+			}
+		}
+	}
+
+	hc := &HookContext{Entity: ec, ID: id, Previous: previous, User: user, Tx: tx}
+	if err := runHooks(beforeDelete, hc); err != nil {
+		return err
+	}
+	if err := row.Delete(nil).Error; err != nil {
+		return err
+	}
+	if err := detachRecord(tx, ec, id); err != nil {
+		return err
+	}
+	if err := breakLock(tx, ec, id); err != nil {
+		return err
+	}
+	if err := runHooks(afterDelete, hc); err != nil {
+		return err
+	}
+	return writeAudit(tx, ec, hc.ID, "delete", user, auditValues(previous))
+}
+
+// withoutID retire id de la liste si la référence porte sur la même table.
+func withoutID(ids []string, sameTable bool, id string) []string {
+	if !sameTable {
+		return ids
+	}
+	out := ids[:0]
+	for _, v := range ids {
+		if v != id {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// fieldLabel renvoie le libellé d'un champ d'une entité, ou son nom à défaut.
+func fieldLabel(ec *entity.EntityConfig, name string) string {
+	if f, ok := ec.FieldsByName[name]; ok && f.Label != "" {
+		return f.Label
+	}
+	return name
+}
@@ -0,0 +1,107 @@
+// internal/crud/integrity_test.go
+package crud
+
+import (
+	"reflect"
+	"testing"
+
+	"example.com/go-crud/internal/entity"
+)
+
+// integrityEntity décrit une table compte : clé générée, contrainte unique sur le numéro
+// bancaire (message déclaré) et sur le nom (message par défaut), référence vers banque.
+func integrityEntity(table, refTable string) *entity.EntityConfig {
+	fields := []entity.Field{
+		{Name: "id", Type: "int"},
+		{Name: "agence", Type: "int"},
+		{Name: "guichet", Type: "int"},
+		{Name: "numero", Type: "int"},
+		{Name: "nom", Type: "string", Label: "Nom"},
+		{Name: "banque_id", Type: "int", References: &entity.ReferenceConfig{Table: refTable, Field: "id"}},
+	}
+	ec := &entity.EntityConfig{
+		Name:       "compte",
+		Table:      table,
+		PrimaryKey: []string{"id"},
+		Fields:     fields,
+		Uniques: []entity.UniqueConfig{
+			{Fields: []string{"agence", "guichet", "numero"}, Message: "Compte en double"},
+			{Fields: []string{"nom"}},
+		},
+		FieldsByName: make(map[string]entity.Field),
+	}
+	for _, f := range fields {
+		ec.FieldsByName[f.Name] = f
+	}
+	return ec
+}
+
+func TestCheckConstraints(t *testing.T) {
+	db := openDialect(t, "sqlite")
+	for _, q := range []string{
+		"CREATE TABLE banque (id INTEGER PRIMARY KEY)",
+		"INSERT INTO banque (id) VALUES (1)",
+		"CREATE TABLE compte (id INTEGER PRIMARY KEY, agence INTEGER, guichet INTEGER, numero INTEGER, nom TEXT, banque_id INTEGER)",
+		"INSERT INTO compte VALUES (1, 10, 20, 100, 'A', 1), (2, 10, 20, 200, 'B', 1)",
+		"CREATE TABLE code (code TEXT PRIMARY KEY)",
+		"INSERT INTO code VALUES ('X')",
+	} {
+		if err := db.Exec(q).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &crudHandler{db: db, ec: integrityEntity("compte", "banque")}
+	codes := &crudHandler{db: db, ec: &entity.EntityConfig{
+		Name: "code", Table: "code", PrimaryKey: []string{"code"},
+		FieldsByName: map[string]entity.Field{"code": {Name: "code", Type: "string"}},
+	}}
+
+	tests := []struct {
+		name string
+		h    *crudHandler
+		vals map[string]interface{}
+		id   string
+		want map[string]string
+	}{
+		{"création sans doublon", h, map[string]interface{}{"agence": 10, "guichet": 20, "numero": 300, "nom": "C", "banque_id": 1}, "", map[string]string{}},
+		{"création en double", h, map[string]interface{}{"agence": 10, "guichet": 20, "numero": 100, "nom": "C"}, "", map[string]string{"agence": "Compte en double"}},
+		{"NULL n'est pas un doublon", h, map[string]interface{}{"agence": 10, "guichet": 20, "numero": nil, "nom": nil}, "", map[string]string{}},
+		{"modification de soi-même", h, map[string]interface{}{"agence": 10, "guichet": 20, "numero": 100, "nom": "A"}, "1", map[string]string{}},
+		{"modification en double", h, map[string]interface{}{"numero": 100}, "2", map[string]string{"agence": "Compte en double"}},
+		{"champ absent lu en base", h, map[string]interface{}{"nom": "A"}, "2", map[string]string{"nom": "La valeur « A » existe déjà pour Nom."}},
+		{"référence inconnue", h, map[string]interface{}{"banque_id": 9}, "1", map[string]string{"banque_id": "La valeur « 9 » n'existe pas dans banque."}},
+		{"référence vide", h, map[string]interface{}{"banque_id": nil}, "1", map[string]string{}},
+		{"clé saisie en double", codes, map[string]interface{}{"code": "X"}, "", map[string]string{"code": "La valeur « X » existe déjà pour code."}},
+		{"clé saisie nouvelle", codes, map[string]interface{}{"code": "Y"}, "", map[string]string{}},
+	}
+	for _, tc := range tests {
+		got, err := tc.h.checkConstraints(db, tc.vals, tc.id)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s : checkConstraints = %v, %v ; attendu %v", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+// Une requête de vérification en échec fait échouer l'enregistrement au lieu de le laisser passer.
+func TestCheckConstraintsErrors(t *testing.T) {
+	db := openDialect(t, "sqlite")
+	db.Exec("CREATE TABLE compte (id INTEGER PRIMARY KEY, agence INTEGER, guichet INTEGER, numero INTEGER, nom TEXT, banque_id INTEGER)")
+	db.Exec("INSERT INTO compte VALUES (1, 10, 20, 100, 'A', NULL)")
+	tests := []struct {
+		name string
+		ec   *entity.EntityConfig
+		vals map[string]interface{}
+		id   string
+	}{
+		{"table absente", integrityEntity("absente", "banque"), map[string]interface{}{"nom": "B"}, ""},
+		{"table référencée absente", integrityEntity("compte", "absente"), map[string]interface{}{"banque_id": 1}, ""},
+		{"enregistrement introuvable", integrityEntity("compte", "banque"), map[string]interface{}{"nom": "B"}, "42"},
+		{"clé invalide", integrityEntity("compte", "banque"), map[string]interface{}{"nom": "B"}, "1,2"},
+	}
+	for _, tc := range tests {
+		h := &crudHandler{db: db, ec: tc.ec}
+		if got, err := h.checkConstraints(db, tc.vals, tc.id); err == nil {
+			t.Errorf("%s : checkConstraints = %v, erreur attendue", tc.name, got)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// internal/crud/keygen.go
+package crud
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"example.com/go-crud/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// KeySequence est le compteur d'une séquence de clés métier. Le nom contient la partie
+// fixe de la clé (ex. "compte:CPT-2026-") : la numérotation repart à 1 chaque année.
+type KeySequence struct {
+	Name  string `gorm:"primaryKey;size:150"`
+	Value int64
+}
+
+// generateKey renseigne dans vals la clé du nouvel enregistrement selon la stratégie de
+// l'entité (rien pour autoincrement : la base l'attribue). La séquence est incrémentée
+// dans la transaction tx : un numéro n'est consommé que si la création aboutit.
+func generateKey(tx *gorm.DB, ec *entity.EntityConfig, vals map[string]interface{}) error {
+	kg := ec.KeyGeneration
+	if kg == nil {
+		return nil
+	}
+	col := ec.PrimaryKey[0]
+	switch kg.Strategy {
+	case "uuidv7":
+		vals[col] = newUUIDv7(time.Now())
+	case "ulid":
+		vals[col] = newULID(time.Now())
+	case "sequence":
+		key, err := nextSequenceKey(tx, ec, kg.Format, time.Now())
+		if err != nil {
+			return err
+		}
+		vals[col] = key
+	}
+	return nil
+}
+
+// newUUIDv7 renvoie un UUID version 7 (RFC 9562) : horodatage en millisecondes suivi d'aléa,
+// ce qui garde les clés triées par date de création.
+func newUUIDv7(now time.Time) string {
+	var b [16]byte
+	rand.Read(b[6:])
+	ms := uint64(now.UnixMilli())
+	binary.BigEndian.PutUint16(b[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+	b[6] = b[6]&0x0f | 0x70 // Version 7
+	b[8] = b[8]&0x3f | 0x80 // Variante RFC 4122
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// crockford est l'alphabet base 32 des ULID (sans I, L, O ni U).
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID renvoie un ULID : 48 bits d'horodatage en millisecondes et 80 bits d'aléa,
+// encodés en 26 caractères base 32 de Crockford.
+func newULID(now time.Time) string {
+	var b [16]byte
+	ms := uint64(now.UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	rand.Read(b[6:])
+	// 128 bits lus par groupes de 5 bits, précédés de 2 bits à zéro
+	out := make([]byte, 26)
+	hi := binary.BigEndian.Uint64(b[0:8])
+	lo := binary.BigEndian.Uint64(b[8:16])
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+// seqPlaceholder repère les éléments d'un format de séquence : {year}, {month}, {day}, {seq}, {seq:N}.
+var seqPlaceholder = regexp.MustCompile(`\{(year|month|day|seq)(?::(\d+))?\}`)
+
+// nextSequenceKey incrémente la séquence du format et renvoie la clé formatée. Le compteur
+// est mis à jour par un UPDATE ... SET value = value + 1, qui verrouille la ligne jusqu'à
+// la fin de la transaction : deux créations simultanées ne peuvent pas obtenir le même numéro.
+func nextSequenceKey(tx *gorm.DB, ec *entity.EntityConfig, format string, now time.Time) (string, error) {
+	render := func(seq int64) string {
+		return seqPlaceholder.ReplaceAllStringFunc(format, func(m string) string {
+			parts := seqPlaceholder.FindStringSubmatch(m)
+			switch parts[1] {
+			case "year":
+				return now.Format("2006")
+			case "month":
+				return now.Format("01")
+			case "day":
+				return now.Format("02")
+			}
+			if seq == 0 {
+				return "{seq}"
+			}
+			width, _ := strconv.Atoi(parts[2])
+			return fmt.Sprintf("%0*d", width, seq)
+		})
+	}
+	name := ec.Name + ":" + render(0)
+
+	res := tx.Model(&KeySequence{}).Where("name = ?", name).Update("value", gorm.Expr("value + 1"))
+	if res.Error != nil {
+		return "", res.Error
+	}
+	if res.RowsAffected == 0 {
+		// Première clé de la séquence (création concurrente possible : on réessaie l'incrément)
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&KeySequence{Name: name}).Error; err != nil {
+			return "", err
+		}
+		if err := tx.Model(&KeySequence{}).Where("name = ?", name).Update("value", gorm.Expr("value + 1")).Error; err != nil {
+			return "", err
+		}
+	}
+	var seq KeySequence
+	if err := tx.Where("name = ?", name).Take(&seq).Error; err != nil {
+		return "", err
+	}
+	return render(seq.Value), nil
+}
@@ -0,0 +1,151 @@
+// internal/crud/keygen_test.go
+package crud
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/go-crud/internal/entity"
+)
+
+var (
+	uuidv7Format = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulidFormat   = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+)
+
+func TestNewUUIDv7(t *testing.T) {
+	times := []time.Time{
+		time.UnixMilli(0),
+		time.Date(2026, 1, 2, 3, 4, 5, 6_000_000, time.UTC),
+		time.Date(2026, 1, 2, 3, 4, 5, 7_000_000, time.UTC),
+		time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+	prev := ""
+	for _, now := range times {
+		id := newUUIDv7(now)
+		if !uuidv7Format.MatchString(id) {
+			t.Errorf("newUUIDv7(%v) = %q : format invalide", now, id)
+			continue
+		}
+		// Les 48 premiers bits sont l'horodatage en millisecondes
+		ms, _ := strconv.ParseUint(strings.ReplaceAll(id[:13], "-", ""), 16, 64)
+		if int64(ms) != now.UnixMilli() {
+			t.Errorf("newUUIDv7(%v) : horodatage %d, attendu %d", now, ms, now.UnixMilli())
+		}
+		if id <= prev {
+			t.Errorf("newUUIDv7 non trié : %q après %q", id, prev)
+		}
+		prev = id
+	}
+	if a, b := newUUIDv7(times[1]), newUUIDv7(times[1]); a == b {
+		t.Errorf("newUUIDv7 : deux clés identiques %q", a)
+	}
+}
+
+func TestNewULID(t *testing.T) {
+	times := []time.Time{
+		time.UnixMilli(0),
+		time.Date(2026, 1, 2, 3, 4, 5, 6_000_000, time.UTC),
+		time.Date(2026, 1, 2, 3, 4, 5, 7_000_000, time.UTC),
+		time.UnixMilli(1<<48 - 1), // Plus grand horodatage d'un ULID
+	}
+	prev := ""
+	for _, now := range times {
+		id := newULID(now)
+		if !ulidFormat.MatchString(id) {
+			t.Errorf("newULID(%v) = %q : format invalide", now, id)
+			continue
+		}
+		// Les 10 premiers caractères encodent l'horodatage en millisecondes
+		var ms int64
+		for _, ch := range id[:10] {
+			ms = ms<<5 | int64(strings.IndexRune(crockford, ch))
+		}
+		if ms != now.UnixMilli() {
+			t.Errorf("newULID(%v) : horodatage %d, attendu %d", now, ms, now.UnixMilli())
+		}
+		if id <= prev {
+			t.Errorf("newULID non trié : %q après %q", id, prev)
+		}
+		prev = id
+	}
+	if a, b := newULID(times[1]), newULID(times[1]); a == b {
+		t.Errorf("newULID : deux clés identiques %q", a)
+	}
+}
+
+func TestNextSequenceKey(t *testing.T) {
+	db := openDialect(t, "sqlite")
+	if err := db.AutoMigrate(&KeySequence{}); err != nil {
+		t.Fatal(err)
+	}
+	compte := &entity.EntityConfig{Name: "compte"}
+	facture := &entity.EntityConfig{Name: "facture"}
+	jan := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, 2, 3, 0, 0, 0, 0, time.UTC)
+	next := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ec     *entity.EntityConfig
+		format string
+		now    time.Time
+		want   string
+	}{
+		{compte, "CPT-{year}-{seq:4}", jan, "CPT-2026-0001"},
+		{compte, "CPT-{year}-{seq:4}", feb, "CPT-2026-0002"},
+		{compte, "CPT-{year}-{seq:4}", next, "CPT-2027-0001"}, // Nouvelle année : nouvelle séquence
+		{compte, "CPT-{year}-{seq:4}", jan, "CPT-2026-0003"},
+		{facture, "CPT-{year}-{seq:4}", jan, "CPT-2026-0001"}, // Séquence propre à l'entité
+		{facture, "F{year}{month}{day}-{seq}", feb, "F20260203-1"},
+		{facture, "F{year}{month}{day}-{seq}", feb, "F20260203-2"},
+		{facture, "{seq:2}", jan, "01"},
+	}
+	for _, tc := range tests {
+		got, err := nextSequenceKey(db, tc.ec, tc.format, tc.now)
+		if err != nil || got != tc.want {
+			t.Errorf("nextSequenceKey(%s, %q, %s) = %q, %v ; attendu %q", tc.ec.Name, tc.format, tc.now.Format("2006-01-02"), got, err, tc.want)
+		}
+	}
+
+	// Un numéro n'est consommé que si la transaction aboutit
+	tx := db.Begin()
+	if got, err := nextSequenceKey(tx, compte, "C{seq}", jan); err != nil || got != "C1" {
+		t.Errorf("nextSequenceKey dans une transaction = %q, %v", got, err)
+	}
+	tx.Rollback()
+	if got, err := nextSequenceKey(db, compte, "C{seq}", jan); err != nil || got != "C1" {
+		t.Errorf("nextSequenceKey après annulation = %q, %v ; attendu C1", got, err)
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	db := openDialect(t, "sqlite")
+	if err := db.AutoMigrate(&KeySequence{}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		kg    *entity.KeyGenerationConfig
+		match func(string) bool
+	}{
+		{&entity.KeyGenerationConfig{Strategy: "uuidv7"}, uuidv7Format.MatchString},
+		{&entity.KeyGenerationConfig{Strategy: "ulid"}, ulidFormat.MatchString},
+		{&entity.KeyGenerationConfig{Strategy: "sequence", Format: "K{seq:3}"}, func(s string) bool { return s == "K001" }},
+	}
+	for _, tc := range tests {
+		ec := &entity.EntityConfig{Name: "essai", PrimaryKey: []string{"code"}, KeyGeneration: tc.kg}
+		vals := map[string]interface{}{}
+		if err := generateKey(db, ec, vals); err != nil {
+			t.Errorf("generateKey(%s) : %v", tc.kg.Strategy, err)
+			continue
+		}
+		if s, _ := vals["code"].(string); !tc.match(s) {
+			t.Errorf("generateKey(%s) = %#v", tc.kg.Strategy, vals["code"])
+		}
+	}
+	vals := map[string]interface{}{}
+	if err := generateKey(db, &entity.EntityConfig{PrimaryKey: []string{"id"}}, vals); err != nil || len(vals) != 0 {
+		t.Errorf("generateKey sans stratégie = %v, %v", vals, err)
+	}
+}
@@ -0,0 +1,147 @@
+// internal/crud/keys.go
+package crud
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"example.com/go-crud/internal/entity"
+	"gorm.io/gorm"
+)
+
+// Une clé d'enregistrement circule dans les URL (/edit/<clé>, highlight=, ids des actions
+// groupées) sous forme encodée : les colonnes de la clé primaire sont séparées par des
+// virgules, et tout octet hors [A-Za-z0-9_-] est écrit ~XX. La clé encodée ne contient
+// donc ni '/', ni '%', ni caractère à échapper dans un chemin, une requête ou un template.
+
+// encodeKey encode les valeurs des colonnes d'une clé.
+func encodeKey(parts []string) string {
+	var b strings.Builder
+	for i, p := range parts {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		for j := 0; j < len(p); j++ {
+			ch := p[j]
+			if ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '_' || ch == '-' {
+				b.WriteByte(ch)
+			} else {
+				fmt.Fprintf(&b, "~%02X", ch)
+			}
+		}
+	}
+	return b.String()
+}
+
+// decodeKey relit une clé encodée de n colonnes.
+func decodeKey(s string, n int) ([]string, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("clé invalide « %s »", s)
+	}
+	for i, p := range parts {
+		if !strings.Contains(p, "~") {
+			continue
+		}
+		var b strings.Builder
+		for j := 0; j < len(p); j++ {
+			if p[j] != '~' {
+				b.WriteByte(p[j])
+				continue
+			}
+			if j+2 >= len(p) {
+				return nil, fmt.Errorf("clé invalide « %s »", s)
+			}
+			v, err := strconv.ParseUint(p[j+1:j+3], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("clé invalide « %s »", s)
+			}
+			b.WriteByte(byte(v))
+			j += 2
+		}
+		parts[i] = b.String()
+	}
+	return parts, nil
+}
+
+// keyPart renvoie la valeur d'une colonne de clé sous forme de texte.
+func keyPart(v interface{}) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// rowKey renvoie la clé encodée d'une ligne lue en base.
+func rowKey(ec *entity.EntityConfig, row map[string]interface{}) string {
+	parts := make([]string, len(ec.PrimaryKey))
+	for i, col := range ec.PrimaryKey {
+		parts[i] = keyPart(row[col])
+	}
+	return encodeKey(parts)
+}
+
+// keyCondition renvoie la condition SQL qui désigne l'enregistrement de clé encodée key.
+// prefix qualifie les colonnes (table ou alias suivi d'un point), vide sinon.
+func keyCondition(ec *entity.EntityConfig, prefix, key string) (string, []interface{}, error) {
+	parts, err := decodeKey(key, len(ec.PrimaryKey))
+	if err != nil {
+		return "", nil, err
+	}
+	conds := make([]string, len(parts))
+	args := make([]interface{}, len(parts))
+	for i, col := range ec.PrimaryKey {
+		conds[i] = prefix + col + " = ?"
+		args[i] = parts[i]
+	}
+	return strings.Join(conds, " AND "), args, nil
+}
+
+// whereKey restreint q à l'enregistrement de clé encodée key.
+func whereKey(q *gorm.DB, ec *entity.EntityConfig, key string) (*gorm.DB, error) {
+	where, args, err := keyCondition(ec, "", key)
+	if err != nil {
+		return nil, err
+	}
+	return q.Where(where, args...), nil
+}
+
+// keysCondition renvoie la condition SQL qui désigne un ensemble d'enregistrements
+// (IN pour une clé simple, disjonction pour une clé composite).
+func keysCondition(ec *entity.EntityConfig, keys []string) (string, []interface{}, error) {
+	if len(ec.PrimaryKey) == 1 {
+		values := make([]string, len(keys))
+		for i, k := range keys {
+			parts, err := decodeKey(k, 1)
+			if err != nil {
+				return "", nil, err
+			}
+			values[i] = parts[0]
+		}
+		return ec.PrimaryKey[0] + " IN ?", []interface{}{values}, nil
+	}
+	conds := make([]string, len(keys))
+	var args []interface{}
+	for i, k := range keys {
+		where, a, err := keyCondition(ec, "", k)
+		if err != nil {
+			return "", nil, err
+		}
+		conds[i] = "(" + where + ")"
+		args = append(args, a...)
+	}
+	return strings.Join(conds, " OR "), args, nil
+}
+
+// keyOrder renvoie le tri sur les colonnes de la clé primaire.
+func keyOrder(ec *entity.EntityConfig, dir string) string {
+	cols := make([]string, len(ec.PrimaryKey))
+	for i, col := range ec.PrimaryKey {
+		cols[i] = col + " " + dir
+	}
+	return strings.Join(cols, ", ")
+}
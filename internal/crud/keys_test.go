@@ -0,0 +1,90 @@
+// internal/crud/keys_test.go
+package crud
+
+import (
+	"reflect"
+	"testing"
+
+	"example.com/go-crud/internal/entity"
+)
+
+func TestEncodeDecodeKey(t *testing.T) {
+	tests := []struct {
+		parts []string
+		want  string
+	}{
+		{[]string{"42"}, "42"},
+		{[]string{"ABC_def-9"}, "ABC_def-9"},
+		{[]string{"a/b"}, "a~2Fb"},
+		{[]string{"50%"}, "50~25"},
+		{[]string{"x,y"}, "x~2Cy"},
+		{[]string{"~"}, "~7E"},
+		{[]string{"é"}, "~C3~A9"},
+		{[]string{"<script>"}, "~3Cscript~3E"},
+		{[]string{""}, ""},
+		{[]string{"FR", "2024", "a b"}, "FR,2024,a~20b"},
+		{[]string{"", ""}, ","},
+	}
+	for _, tc := range tests {
+		got := encodeKey(tc.parts)
+		if got != tc.want {
+			t.Errorf("encodeKey(%q) = %q, attendu %q", tc.parts, got, tc.want)
+		}
+		back, err := decodeKey(got, len(tc.parts))
+		if err != nil || !reflect.DeepEqual(back, tc.parts) {
+			t.Errorf("decodeKey(%q) = %q, %v ; attendu %q", got, back, err, tc.parts)
+		}
+	}
+}
+
+func TestDecodeKeyInvalid(t *testing.T) {
+	tests := []struct {
+		key string
+		n   int
+	}{
+		{"1,2", 1},  // Trop de colonnes
+		{"1", 2},    // Pas assez
+		{"a~2", 1},  // Échappement tronqué
+		{"a~", 1},   // Échappement tronqué
+		{"a~ZZ", 1}, // Pas de l'hexadécimal
+		{"~-1", 1},
+	}
+	for _, tc := range tests {
+		if parts, err := decodeKey(tc.key, tc.n); err == nil {
+			t.Errorf("decodeKey(%q, %d) = %q, erreur attendue", tc.key, tc.n, parts)
+		}
+	}
+}
+
+func TestKeyConditions(t *testing.T) {
+	simple := &entity.EntityConfig{PrimaryKey: []string{"id"}}
+	composite := &entity.EntityConfig{PrimaryKey: []string{"pays", "code"}}
+
+	where, args, err := keyCondition(composite, "t.", encodeKey([]string{"FR", "a,b"}))
+	if err != nil || where != "t.pays = ? AND t.code = ?" || !reflect.DeepEqual(args, []interface{}{"FR", "a,b"}) {
+		t.Errorf("keyCondition composite = %q, %v, %v", where, args, err)
+	}
+	if _, _, err := keyCondition(composite, "", "FR"); err == nil {
+		t.Error("keyCondition : clé incomplète acceptée")
+	}
+
+	where, args, err = keysCondition(simple, []string{"1", "a~2Fb"})
+	if err != nil || where != "id IN ?" || !reflect.DeepEqual(args, []interface{}{[]string{"1", "a/b"}}) {
+		t.Errorf("keysCondition simple = %q, %v, %v", where, args, err)
+	}
+	where, args, err = keysCondition(composite, []string{"FR,1", "BE,2"})
+	if err != nil || where != "(pays = ? AND code = ?) OR (pays = ? AND code = ?)" || !reflect.DeepEqual(args, []interface{}{"FR", "1", "BE", "2"}) {
+		t.Errorf("keysCondition composite = %q, %v, %v", where, args, err)
+	}
+	if _, _, err := keysCondition(composite, []string{"FR,1", "BE"}); err == nil {
+		t.Error("keysCondition : clé incomplète acceptée")
+	}
+
+	row := map[string]interface{}{"pays": []byte("FR"), "code": int64(7), "libelle": "x"}
+	if got := rowKey(composite, row); got != "FR,7" {
+		t.Errorf("rowKey = %q", got)
+	}
+	if got := keyOrder(composite, "DESC"); got != "pays DESC, code DESC" {
+		t.Errorf("keyOrder = %q", got)
+	}
+}
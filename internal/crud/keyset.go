@@ -0,0 +1,206 @@
+// internal/crud/keyset.go
+package crud
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// listPage décrit la pagination d'une page de liste (HTML et JSON).
+type listPage struct {
+	Mode        string `json:"mode"` // "offset" ou "keyset"
+	Page        int    `json:"page,omitempty"`
+	PageSize    int    `json:"pageSize"`
+	Total       *int64 `json:"total,omitempty"`       // Absent en mode keyset sans comptage approximatif
+	Approximate bool   `json:"approximate,omitempty"` // Total estimé (mode keyset)
+	TotalPages  int    `json:"totalPages,omitempty"`
+	Next        string `json:"next,omitempty"` // Curseur de la page suivante (paramètre after)
+	Prev        string `json:"prev,omitempty"` // Curseur de la page précédente (paramètre before)
+}
+
+// keyset indique si la liste est paginée par curseur (list.pagination: keyset).
+func (h *crudHandler) keyset() bool {
+	return h.ec.List.Pagination == "keyset"
+}
+
+// encodeCursor encode la position d'une ligne : valeur du champ de tri et clé primaire
+// (une seule colonne en mode keyset).
+func (h *crudHandler) encodeCursor(row map[string]interface{}, sortField string) string {
+	b, _ := json.Marshal([]interface{}{cursorValue(row[sortField]), cursorValue(row[h.ec.PrimaryKey[0]])})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// cursorValue renvoie une valeur lue en base telle qu'elle est écrite dans un curseur : texte
+// pour []byte, RFC 3339 à la nanoseconde pour une date (relue par sortValue).
+func cursorValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case []byte:
+		return string(t)
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	}
+	return v
+}
+
+// sortValue convertit la valeur de tri d'un curseur vers le type de la colonne avant de la
+// comparer : un champ date ou datetime est passé en time.Time, écrit par le pilote comme
+// les valeurs de la colonne (sous SQLite, un texte "2006-01-02 15:04:05" ne serait pas égal
+// à la valeur stockée "2006-01-02 15:04:05+00:00" et la ligne du curseur serait relue).
+func (h *crudHandler) sortValue(field string, v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	switch h.ec.FieldsByName[field].Type {
+	case "date", "datetime":
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t
+		}
+	}
+	return v
+}
+
+// decodeCursor relit un curseur produit par encodeCursor.
+func decodeCursor(s string) (interface{}, interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("curseur invalide")
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var parts []interface{}
+	if err := dec.Decode(&parts); err != nil || len(parts) != 2 || parts[1] == nil {
+		return nil, nil, fmt.Errorf("curseur invalide")
+	}
+	for i, p := range parts {
+		if n, ok := p.(json.Number); ok {
+			if v, err := n.Int64(); err == nil {
+				parts[i] = v
+			} else {
+				parts[i], _ = n.Float64()
+			}
+		}
+	}
+	return parts[0], parts[1], nil
+}
+
+// keysetAfter renvoie la condition des lignes situées après le curseur dans l'ordre de
+// parcours (champ de tri puis clé key, croissant si asc). Une valeur NULL est la plus petite
+// (tri NULLS FIRST imposé sous PostgreSQL, voir nullsFirst).
+func keysetAfter(field, key string, asc bool, value, id interface{}) (string, []interface{}) {
+	if field == key {
+		if asc {
+			return key + " > ?", []interface{}{id}
+		}
+		return key + " < ?", []interface{}{id}
+	}
+	switch {
+	case asc && value == nil:
+		return fmt.Sprintf("((%s IS NULL AND %s > ?) OR %s IS NOT NULL)", field, key, field), []interface{}{id}
+	case asc:
+		return fmt.Sprintf("(%s > ? OR (%s = ? AND %s > ?))", field, field, key), []interface{}{value, value, id}
+	case value == nil:
+		return fmt.Sprintf("(%s IS NULL AND %s < ?)", field, key), []interface{}{id}
+	default:
+		return fmt.Sprintf("(%s < ? OR (%s = ? AND %s < ?) OR %s IS NULL)", field, field, key, field), []interface{}{value, value, id}
+	}
+}
+
+// keysetPage lit une page par curseur : paramètre after (page suivante) ou before (page
+// précédente), sans OFFSET. Une ligne de plus est lue pour savoir s'il reste des lignes.
+func (h *crudHandler) keysetPage(c *gin.Context, query *gorm.DB, sortField, sortOrder string, pageSize int) ([]map[string]interface{}, listPage, error) {
+	pg := listPage{Mode: "keyset", PageSize: pageSize}
+	key := h.ec.PrimaryKey[0]
+	forward := true
+	cursor := c.Query("after")
+	if before := c.Query("before"); before != "" {
+		cursor, forward = before, false
+	}
+
+	// Une page précédente se lit dans l'ordre inverse, puis est remise dans l'ordre affiché
+	scanAsc := (sortOrder == "asc") == forward
+	dir := "DESC"
+	if scanAsc {
+		dir = "ASC"
+	}
+	if cursor != "" {
+		value, id, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, pg, err
+		}
+		where, args := keysetAfter(sortField, key, scanAsc, h.sortValue(sortField, value), id)
+		query = query.Where(where, args...)
+	}
+	if sortField != key {
+		query = query.Order(sortField + " " + nullsFirst(query, dir))
+	}
+	var data []map[string]interface{}
+	if err := query.Order(key + " " + dir).Limit(pageSize + 1).Find(&data).Error; err != nil {
+		return nil, pg, err
+	}
+
+	more := len(data) > pageSize
+	if more {
+		data = data[:pageSize]
+	}
+	if !forward {
+		slices.Reverse(data)
+	}
+	hasNext, hasPrev := more, cursor != ""
+	if !forward {
+		hasNext, hasPrev = true, more
+	}
+	if len(data) > 0 {
+		if hasNext {
+			pg.Next = h.encodeCursor(data[len(data)-1], sortField)
+		}
+		if hasPrev {
+			pg.Prev = h.encodeCursor(data[0], sortField)
+		}
+	}
+	return data, pg, nil
+}
+
+// approximateCount estime le nombre de lignes de la table sans la parcourir : statistiques
+// du SGBD (sqlite_stat1 après ANALYZE, pg_class, information_schema), sinon plus grand
+// identifiant (clé générée par la base uniquement).
+func (h *crudHandler) approximateCount() (int64, bool) {
+	switch dialect(h.db) {
+	case "sqlite":
+		var stat string
+		if err := h.db.Raw("SELECT stat FROM sqlite_stat1 WHERE tbl = ? LIMIT 1", h.ec.Table).Row().Scan(&stat); err == nil {
+			if f := strings.Fields(stat); len(f) > 0 {
+				if n, err := strconv.ParseInt(f[0], 10, 64); err == nil {
+					return n, true
+				}
+			}
+		}
+	case "postgres":
+		var n float64
+		if err := h.db.Raw("SELECT reltuples FROM pg_class WHERE relname = ?", h.ec.Table).Row().Scan(&n); err == nil && n >= 0 {
+			return int64(n), true
+		}
+	case "mysql":
+		var n int64
+		if err := h.db.Raw("SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", h.ec.Table).Row().Scan(&n); err == nil {
+			return n, true
+		}
+	}
+	if !h.ec.AutoKey() {
+		return 0, false
+	}
+	var maxID *int64
+	if err := h.db.Table(h.ec.Table).Select("MAX(" + h.ec.PrimaryKey[0] + ")").Row().Scan(&maxID); err == nil && maxID != nil {
+		return *maxID, true
+	}
+	return 0, false
+}
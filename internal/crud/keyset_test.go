@@ -0,0 +1,157 @@
+// internal/crud/keyset_test.go
+package crud
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+
+	"example.com/go-crud/internal/entity"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	h := &crudHandler{ec: &entity.EntityConfig{
+		PrimaryKey: []string{"id"},
+		FieldsByName: map[string]entity.Field{
+			"jour":   {Name: "jour", Type: "date"},
+			"maj":    {Name: "maj", Type: "datetime"},
+			"nom":    {Name: "nom", Type: "string"},
+			"taux":   {Name: "taux", Type: "number"},
+			"code":   {Name: "code", Type: "string"},
+			"numero": {Name: "numero", Type: "int"},
+		},
+	}}
+	when := time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC)
+	nano := time.Date(2025, 3, 14, 15, 9, 26, 123456789, time.FixedZone("", 3600))
+	tests := []struct {
+		row       map[string]interface{}
+		sort      string
+		wantValue interface{}
+		wantID    interface{}
+	}{
+		{map[string]interface{}{"id": int64(12), "nom": "Dupont"}, "nom", "Dupont", int64(12)},
+		{map[string]interface{}{"id": int64(3), "nom": []byte("Zoé, \"x\"")}, "nom", "Zoé, \"x\"", int64(3)},
+		{map[string]interface{}{"id": int64(4), "nom": nil}, "nom", nil, int64(4)},
+		{map[string]interface{}{"id": int64(5), "jour": when}, "jour", when, int64(5)},
+		{map[string]interface{}{"id": int64(6), "maj": nano}, "maj", nano, int64(6)},                                       // Fuseau et nanosecondes conservés
+		{map[string]interface{}{"id": int64(10), "nom": "2025-03-14T15:09:26Z"}, "nom", "2025-03-14T15:09:26Z", int64(10)}, // Texte : pas de conversion
+		{map[string]interface{}{"id": int64(7), "taux": 2.5}, "taux", 2.5, int64(7)},
+		{map[string]interface{}{"id": int64(9007199254740993), "numero": int64(-1)}, "numero", int64(-1), int64(9007199254740993)}, // Au-delà des flottants exacts
+		{map[string]interface{}{"id": []byte("01J0ABC"), "code": "x"}, "code", "x", "01J0ABC"},
+		{map[string]interface{}{"id": int64(8)}, "id", int64(8), int64(8)},
+	}
+	for _, tc := range tests {
+		cursor := h.encodeCursor(tc.row, tc.sort)
+		value, id, err := decodeCursor(cursor)
+		value = h.sortValue(tc.sort, value)
+		if want, ok := tc.wantValue.(time.Time); ok {
+			if got, ok := value.(time.Time); ok && got.Equal(want) {
+				value = want
+			}
+		}
+		if err != nil || !reflect.DeepEqual(value, tc.wantValue) || !reflect.DeepEqual(id, tc.wantID) {
+			t.Errorf("decodeCursor(encodeCursor(%v, %s)) = %#v, %#v, %v ; attendu %#v, %#v", tc.row, tc.sort, value, id, err, tc.wantValue, tc.wantID)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"!!!",
+		base64.RawURLEncoding.EncodeToString([]byte(`[1]`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`[1,2,3]`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`[1,null]`)), // Clé absente
+		base64.RawURLEncoding.EncodeToString([]byte(`{"a":1}`)),
+		base64.StdEncoding.EncodeToString([]byte(`["a",1]`)), // Base64 avec remplissage
+	} {
+		if v, id, err := decodeCursor(s); err == nil {
+			t.Errorf("decodeCursor(%q) = %#v, %#v, erreur attendue", s, v, id)
+		}
+	}
+}
+
+func TestKeysetAfter(t *testing.T) {
+	tests := []struct {
+		field, key string
+		asc        bool
+		value      interface{}
+		where      string
+		args       []interface{}
+	}{
+		{"id", "id", true, 5, "id > ?", []interface{}{7}},
+		{"id", "id", false, 5, "id < ?", []interface{}{7}},
+		{"nom", "id", true, "b", "(nom > ? OR (nom = ? AND id > ?))", []interface{}{"b", "b", 7}},
+		{"nom", "id", true, nil, "((nom IS NULL AND id > ?) OR nom IS NOT NULL)", []interface{}{7}},
+		{"nom", "id", false, "b", "(nom < ? OR (nom = ? AND id < ?) OR nom IS NULL)", []interface{}{"b", "b", 7}},
+		{"nom", "id", false, nil, "(nom IS NULL AND id < ?)", []interface{}{7}},
+	}
+	for _, tc := range tests {
+		where, args := keysetAfter(tc.field, tc.key, tc.asc, tc.value, 7)
+		if where != tc.where || !reflect.DeepEqual(args, tc.args) {
+			t.Errorf("keysetAfter(%s, %v, %v) = %q, %v ; attendu %q, %v", tc.field, tc.asc, tc.value, where, args, tc.where, tc.args)
+		}
+	}
+}
+
+// TestKeysetWalk parcourt une table page après page avec les curseurs de keysetPage et
+// vérifie qu'on retrouve l'ordre du tri complet, valeurs NULL et doublons compris, sur une
+// colonne texte et sur une colonne DATETIME écrite en time.Time comme le fait la fiche.
+func TestKeysetWalk(t *testing.T) {
+	db := openDialect(t, "sqlite")
+	db.Exec("CREATE TABLE walk (id INTEGER PRIMARY KEY, nom TEXT, maj DATETIME)")
+	day := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	noms := []interface{}{"b", nil, "a", "b", nil, "c", "a"}
+	majs := []interface{}{day, nil, day.Add(time.Hour), day, day.Add(-time.Hour), nil, day.Add(time.Hour)}
+	for i := range noms {
+		if err := db.Exec("INSERT INTO walk (id, nom, maj) VALUES (?, ?, ?)", i+1, noms[i], majs[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &crudHandler{db: db, ec: &entity.EntityConfig{
+		PrimaryKey: []string{"id"},
+		FieldsByName: map[string]entity.Field{
+			"id":  {Name: "id", Type: "int"},
+			"nom": {Name: "nom", Type: "string"},
+			"maj": {Name: "maj", Type: "datetime"},
+		},
+	}}
+	for _, field := range []string{"nom", "maj"} {
+		for _, asc := range []bool{true, false} {
+			dir := "ASC"
+			if !asc {
+				dir = "DESC"
+			}
+			order := field + " " + nullsFirst(db, dir) + ", id " + dir
+			var want []int64
+			if err := db.Table("walk").Order(order).Pluck("id", &want).Error; err != nil || len(want) != 7 {
+				t.Fatalf("tri complet : %v, %v", want, err)
+			}
+
+			var got []int64
+			q := db.Table("walk").Order(order).Limit(2)
+			for len(got) <= len(want) {
+				var rows []map[string]interface{}
+				if err := q.Find(&rows).Error; err != nil {
+					t.Fatal(err)
+				}
+				for _, r := range rows {
+					got = append(got, r["id"].(int64))
+				}
+				if len(rows) < 2 {
+					break
+				}
+				value, id, err := decodeCursor(h.encodeCursor(rows[len(rows)-1], field))
+				if err != nil {
+					t.Fatal(err)
+				}
+				where, args := keysetAfter(field, "id", asc, h.sortValue(field, value), id)
+				q = db.Table("walk").Order(order).Limit(2).Where(where, args...)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s %s : parcours par curseur %v, attendu %v", field, dir, got, want)
+			}
+		}
+	}
+}
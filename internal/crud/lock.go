@@ -0,0 +1,161 @@
+// internal/crud/lock.go
+package crud
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"example.com/go-crud/internal/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecordLock est le verrou d'un enregistrement ouvert en modification (entity.lock). Il est
+// levé à l'enregistrement, à l'annulation ou à expiration, et peut être forcé par l'administrateur.
+type RecordLock struct {
+	Entity    string `gorm:"primaryKey;size:100"`
+	RecordID  string `gorm:"primaryKey;size:100"`
+	Owner     string `gorm:"size:100"`
+	Token     string `gorm:"size:26"` // Change à chaque prise : une reprise concurrente n'aboutit qu'une fois
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// acquireLock pose ou prolonge le verrou de l'utilisateur sur l'enregistrement id. Si un autre
+// utilisateur détient un verrou valide, il est renvoyé et rien n'est modifié.
+func (h *crudHandler) acquireLock(id, user string) (*RecordLock, error) {
+	var holder *RecordLock
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var current RecordLock
+		res := tx.Where("entity = ? AND record_id = ?", h.ec.Name, id).Limit(1).Find(&current)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 && current.Owner != user && current.ExpiresAt.After(now) {
+			holder = &current
+			return nil
+		}
+
+		lock := RecordLock{Entity: h.ec.Name, RecordID: id, Owner: user, Token: newULID(now), ExpiresAt: now.Add(h.ec.Lock.Duration)}
+		if res.RowsAffected > 0 {
+			// Notre verrou (prolongé) ou un verrou expiré (repris), tant qu'il n'a pas changé
+			res = tx.Model(&RecordLock{}).
+				Where("entity = ? AND record_id = ? AND token = ?", h.ec.Name, id, current.Token).
+				Updates(map[string]interface{}{"owner": user, "token": lock.Token, "expires_at": lock.ExpiresAt})
+		} else {
+			res = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&lock)
+		}
+		if res.Error != nil || res.RowsAffected > 0 {
+			return res.Error
+		}
+
+		// Verrou pris entre-temps par un autre utilisateur
+		if err := tx.Where("entity = ? AND record_id = ?", h.ec.Name, id).Take(&current).Error; err != nil {
+			return err
+		}
+		if current.Owner != user {
+			holder = &current
+		}
+		return nil
+	})
+	return holder, err
+}
+
+// lockView pose le verrou de l'utilisateur et renvoie son affichage dans la fiche : échéance,
+// ou détenteur (fiche en lecture seule) si un autre utilisateur le détient. nil sans entity.lock.
+// Un utilisateur anonyme ne pose pas de verrou : il voit seulement celui d'un autre.
+func (h *crudHandler) lockView(c *gin.Context, id string) (gin.H, error) {
+	if h.ec.Lock == nil {
+		return nil, nil
+	}
+	user := currentUser(c)
+	var holder *RecordLock
+	var err error
+	if user == "" {
+		holder, err = h.lockHolder(id)
+	} else {
+		holder, err = h.acquireLock(id, user)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if holder != nil {
+		return gin.H{"Owner": holder.Owner, "Until": holder.ExpiresAt.Local().Format("15:04"), "CanBreak": isAdmin(c)}, nil
+	}
+	if user == "" {
+		return nil, nil
+	}
+	return gin.H{"Until": time.Now().Add(h.ec.Lock.Duration).Format("15:04")}, nil
+}
+
+// lockHolder renvoie le verrou valide posé sur l'enregistrement id, nil s'il n'y en a pas.
+func (h *crudHandler) lockHolder(id string) (*RecordLock, error) {
+	var current RecordLock
+	res := h.db.Where("entity = ? AND record_id = ? AND expires_at > ?", h.ec.Name, id, time.Now()).Limit(1).Find(&current)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil, res.Error
+	}
+	return &current, nil
+}
+
+// checkLock refuse l'écriture si un autre utilisateur détient un verrou valide sur l'enregistrement.
+func checkLock(tx *gorm.DB, ec *entity.EntityConfig, id, user string) error {
+	if ec.Lock == nil {
+		return nil
+	}
+	var current RecordLock
+	res := tx.Where("entity = ? AND record_id = ?", ec.Name, id).Limit(1).Find(&current)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 && current.Owner != user && current.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("enregistrement verrouillé par %s jusqu'à %s", current.Owner, current.ExpiresAt.Local().Format("15:04"))
+	}
+	return nil
+}
+
+// releaseLock lève le verrou de l'utilisateur ; un utilisateur anonyme n'en détient pas.
+func releaseLock(tx *gorm.DB, ec *entity.EntityConfig, id, user string) error {
+	if ec.Lock == nil || user == "" {
+		return nil
+	}
+	return tx.Where("entity = ? AND record_id = ? AND owner = ?", ec.Name, id, user).Delete(&RecordLock{}).Error
+}
+
+// breakLock lève le verrou de l'enregistrement quel que soit son détenteur (verrou forcé
+// par l'administrateur, enregistrement supprimé).
+func breakLock(tx *gorm.DB, ec *entity.EntityConfig, id string) error {
+	if ec.Lock == nil {
+		return nil
+	}
+	return tx.Where("entity = ? AND record_id = ?", ec.Name, id).Delete(&RecordLock{}).Error
+}
+
+// unlock lève le verrou à l'annulation de la fiche et revient à la liste. Avec force=1,
+// l'administrateur lève le verrou d'un autre utilisateur et rouvre la fiche.
+func (h *crudHandler) unlock(c *gin.Context) {
+	id := c.Param("id")
+	query := c.Request.URL.Query()
+	if query.Get("force") == "" {
+		if err := releaseLock(h.db, h.ec, id, currentUser(c)); err != nil {
+			log.Printf("[LOCK] %s/%s : %v", h.ec.Name, id, err)
+		}
+		c.Redirect(http.StatusSeeOther, "/"+h.ec.List.Name+"?"+query.Encode())
+		return
+	}
+	if !isAdmin(c) {
+		c.String(http.StatusForbidden, "Seul l'administrateur peut forcer un verrou")
+		return
+	}
+	if err := breakLock(h.db, h.ec, id); err != nil {
+		c.String(http.StatusInternalServerError, "Erreur de déverrouillage : %v", err)
+		return
+	}
+	log.Printf("[LOCK] Verrou de %s/%s forcé par %s", h.ec.Name, id, currentUser(c))
+	query.Del("force")
+	c.Redirect(http.StatusSeeOther, "/"+h.ec.Fiche.Name+"/edit/"+id+"?"+query.Encode())
+}
@@ -0,0 +1,173 @@
+// internal/crud/lock_test.go
+package crud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/go-crud/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+func lockHandler(t *testing.T) *crudHandler {
+	t.Helper()
+	db := openDialect(t, "sqlite")
+	if err := db.AutoMigrate(&RecordLock{}); err != nil {
+		t.Fatal(err)
+	}
+	ec := &entity.EntityConfig{Name: "compte", Lock: &entity.LockConfig{Duration: 15 * time.Minute}}
+	ec.List.Name = "compteList"
+	ec.Fiche.Name = "compteFiche"
+	return &crudHandler{db: db, ec: ec}
+}
+
+// lockOwner renvoie le détenteur du verrou valide de l'enregistrement, "" sans verrou.
+func lockOwner(t *testing.T, h *crudHandler, id string) string {
+	t.Helper()
+	holder, err := h.lockHolder(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder == nil {
+		return ""
+	}
+	return holder.Owner
+}
+
+func TestLockAcquireRelease(t *testing.T) {
+	h := lockHandler(t)
+	steps := []struct {
+		name   string
+		do     func() (*RecordLock, error)
+		holder string // Détenteur renvoyé (autre utilisateur), "" si l'action aboutit
+		owner  string // Détenteur du verrou après l'action
+	}{
+		{"alice pose le verrou", func() (*RecordLock, error) { return h.acquireLock("1", "alice") }, "", "alice"},
+		{"bob est refusé", func() (*RecordLock, error) { return h.acquireLock("1", "bob") }, "alice", "alice"},
+		{"alice prolonge", func() (*RecordLock, error) { return h.acquireLock("1", "alice") }, "", "alice"},
+		{"autre enregistrement libre", func() (*RecordLock, error) { return h.acquireLock("2", "bob") }, "", "alice"},
+		{"bob ne lève pas le verrou d'alice", func() (*RecordLock, error) { return nil, releaseLock(h.db, h.ec, "1", "bob") }, "", "alice"},
+		{"un anonyme ne lève pas le verrou", func() (*RecordLock, error) { return nil, releaseLock(h.db, h.ec, "1", "") }, "", "alice"},
+		{"alice lève son verrou", func() (*RecordLock, error) { return nil, releaseLock(h.db, h.ec, "1", "alice") }, "", ""},
+		{"bob pose le verrou", func() (*RecordLock, error) { return h.acquireLock("1", "bob") }, "", "bob"},
+		{"verrou forcé", func() (*RecordLock, error) { return nil, breakLock(h.db, h.ec, "1") }, "", ""},
+	}
+	for _, s := range steps {
+		holder, err := s.do()
+		if err != nil {
+			t.Fatalf("%s : %v", s.name, err)
+		}
+		got := ""
+		if holder != nil {
+			got = holder.Owner
+		}
+		if got != s.holder {
+			t.Errorf("%s : détenteur renvoyé %q, attendu %q", s.name, got, s.holder)
+		}
+		if owner := lockOwner(t, h, "1"); owner != s.owner {
+			t.Errorf("%s : verrou détenu par %q, attendu %q", s.name, owner, s.owner)
+		}
+	}
+}
+
+func TestLockExpired(t *testing.T) {
+	h := lockHandler(t)
+	if _, err := h.acquireLock("1", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	h.db.Model(&RecordLock{}).Where("record_id = ?", "1").Update("expires_at", time.Now().Add(-time.Minute))
+	if err := checkLock(h.db, h.ec, "1", "bob"); err != nil {
+		t.Errorf("checkLock sur un verrou expiré : %v", err)
+	}
+	if holder, err := h.acquireLock("1", "bob"); err != nil || holder != nil {
+		t.Errorf("reprise d'un verrou expiré = %v, %v", holder, err)
+	}
+	if owner := lockOwner(t, h, "1"); owner != "bob" {
+		t.Errorf("verrou repris détenu par %q", owner)
+	}
+}
+
+func TestCheckLock(t *testing.T) {
+	h := lockHandler(t)
+	if _, err := h.acquireLock("1", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		user    string
+		id      string
+		wantErr bool
+	}{
+		{"alice", "1", false},
+		{"bob", "1", true},
+		{"", "1", true}, // Un anonyme n'écrit pas sur un enregistrement verrouillé
+		{"bob", "2", false},
+	}
+	for _, tc := range tests {
+		if err := checkLock(h.db, h.ec, tc.id, tc.user); (err != nil) != tc.wantErr {
+			t.Errorf("checkLock(%s, %q) = %v", tc.id, tc.user, err)
+		}
+	}
+	if err := checkLock(h.db, &entity.EntityConfig{Name: "compte"}, "1", "bob"); err != nil {
+		t.Errorf("checkLock sans entity.lock = %v", err)
+	}
+}
+
+func TestLockViewAnonymous(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := lockHandler(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/compteFiche/edit/1", nil)
+
+	// Sans verrou : rien à afficher, et aucun verrou posé
+	if view, err := h.lockView(c, "1"); err != nil || view != nil {
+		t.Errorf("lockView anonyme = %v, %v", view, err)
+	}
+	if owner := lockOwner(t, h, "1"); owner != "" {
+		t.Errorf("un anonyme a posé un verrou (%q)", owner)
+	}
+
+	// Verrou d'un autre : fiche en lecture seule
+	if _, err := h.acquireLock("1", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	view, err := h.lockView(c, "1")
+	if err != nil || view == nil || view["Owner"] != "alice" || view["CanBreak"] != false {
+		t.Errorf("lockView anonyme sur un verrou d'alice = %v, %v", view, err)
+	}
+}
+
+func TestUnlockForce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetAdminUser("admin")
+	t.Cleanup(func() { SetAdminUser("") })
+	tests := []struct {
+		user   string
+		status int
+		owner  string // Détenteur du verrou d'alice après la requête
+	}{
+		{"", http.StatusForbidden, "alice"},
+		{"bob", http.StatusForbidden, "alice"},
+		{"admin", http.StatusSeeOther, ""},
+	}
+	for _, tc := range tests {
+		h := lockHandler(t)
+		if _, err := h.acquireLock("1", "alice"); err != nil {
+			t.Fatal(err)
+		}
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/compteFiche/unlock/1?force=1", nil)
+		c.Params = gin.Params{{Key: "id", Value: "1"}}
+		if tc.user != "" {
+			c.Set("user", tc.user)
+		}
+		h.unlock(c)
+		if got := c.Writer.Status(); got != tc.status {
+			t.Errorf("%q : statut %d, attendu %d", tc.user, got, tc.status)
+		}
+		if owner := lockOwner(t, h, "1"); owner != tc.owner {
+			t.Errorf("%q : verrou détenu par %q, attendu %q", tc.user, owner, tc.owner)
+		}
+	}
+}
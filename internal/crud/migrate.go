@@ -0,0 +1,19 @@
+// internal/crud/migrate.go
+package crud
+
+import "gorm.io/gorm"
+
+// Migrate crée ou met à jour les tables techniques utilisées par les handlers CRUD
+// (vues enregistrées, préférences de colonnes...). Les tables des entités n'en font pas partie :
+// seul setupFullText leur ajoute des déclencheurs (_ai, _ad, _au) qui tiennent l'index FTS5 à jour.
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&SavedView{},
+		&ColumnPreference{},
+		&AuditEntry{},
+		&KeySequence{},
+		&RecordVersion{},
+		&RecordLock{},
+		&Attachment{},
+	)
+}
@@ -0,0 +1,210 @@
+// internal/crud/money.go
+package crud
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"example.com/go-crud/internal/entity"
+)
+
+// Les montants des champs money sont manipulés en unités mineures (centimes) dans un int64 :
+// saisie, conversion et agrégats n'utilisent jamais de flottant.
+
+// parseMoney lit un montant saisi ("1 846,67", "-12,5", "1,846.67") en unités mineures.
+// Un seul séparateur est la marque décimale ; s'il y en a deux sortes, c'est le dernier.
+// Les décimales au-delà de celles de la devise sont refusées, sauf des zéros.
+func parseMoney(s string, decimals int) (int64, error) {
+	v := strings.NewReplacer(" ", "", " ", "", " ", "").Replace(strings.TrimSpace(s))
+	neg := false
+	if strings.HasPrefix(v, "-") || strings.HasPrefix(v, "+") {
+		neg = v[0] == '-'
+		v = v[1:]
+	}
+	intPart, frac := v, ""
+	if i := strings.LastIndexAny(v, ",."); i >= 0 {
+		mark := v[i]
+		other := byte(',')
+		if mark == ',' {
+			other = '.'
+		}
+		switch {
+		case strings.IndexByte(v, other) >= 0 || strings.IndexByte(v[:i], mark) < 0:
+			// "1.846,67", "1,846.67" ou "12,5" : le dernier séparateur est décimal
+			intPart, frac = strings.NewReplacer(",", "", ".", "").Replace(v[:i]), v[i+1:]
+		default:
+			// "1.846.670" : séparateurs de milliers seulement
+			intPart = strings.ReplaceAll(v, string(mark), "")
+		}
+	}
+	if intPart == "" && frac == "" || !digitsOnly(intPart) || !digitsOnly(frac) {
+		return 0, fmt.Errorf("format non reconnu")
+	}
+	if len(frac) > decimals {
+		if strings.Trim(frac[decimals:], "0") != "" {
+			return 0, fmt.Errorf("%d décimales au plus", decimals)
+		}
+		frac = frac[:decimals]
+	}
+	digits := strings.TrimLeft(intPart+frac+strings.Repeat("0", decimals-len(frac)), "0")
+	if digits == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("montant hors limites")
+	}
+	if neg {
+		n = -n
+	}
+	return n, nil
+}
+
+// digitsOnly indique si s ne contient que des chiffres.
+func digitsOnly(s string) bool {
+	return strings.Trim(s, "0123456789") == ""
+}
+
+// moneyValue lit un montant tel que la base le renvoie (entier, texte décimal, flottant
+// d'une colonne REAL) et renvoie ses unités mineures ; false pour une valeur nulle ou illisible.
+func moneyValue(raw interface{}, m *entity.MoneyConfig) (int64, bool) {
+	var s string
+	switch v := raw.(type) {
+	case nil:
+		return 0, false
+	case []byte:
+		s = string(v)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64) // Plus courte écriture exacte : 1846.67
+	default:
+		s = fmt.Sprint(v)
+	}
+	decimals := m.Digits
+	if m.Storage == "minor" {
+		decimals = 0
+	}
+	n, err := parseMoney(s, decimals)
+	return n, err == nil
+}
+
+// storeMoney renvoie la valeur à écrire en base : l'entier des unités mineures, ou le
+// texte décimal ("1846.67") pour une colonne DECIMAL.
+func storeMoney(minor int64, m *entity.MoneyConfig) interface{} {
+	if m.Storage == "minor" {
+		return minor
+	}
+	return formatMinor(minor, m.Digits, ".", "")
+}
+
+// moneyColumn renvoie l'expression SQL des unités mineures d'une colonne money, pour des
+// agrégats exacts : la colonne elle-même, ou son montant décimal arrondi au centime.
+func moneyColumn(col string, m *entity.MoneyConfig) string {
+	if m.Storage == "minor" || m.Digits == 0 {
+		return col
+	}
+	return fmt.Sprintf("ROUND(%s * %s)", col, "1"+strings.Repeat("0", m.Digits))
+}
+
+// roundMinor lit le résultat d'un agrégat sur des unités mineures (entier, NUMERIC ou
+// flottant d'une moyenne) et l'arrondit à l'unité, au plus proche en s'éloignant de zéro.
+func roundMinor(raw interface{}) (int64, bool) {
+	if b, ok := raw.([]byte); ok {
+		raw = string(b)
+	}
+	r := new(big.Rat)
+	switch v := raw.(type) {
+	case nil:
+		return 0, false
+	case int64:
+		return v, true
+	case float64:
+		if r.SetFloat64(v) == nil {
+			return 0, false
+		}
+	default:
+		if _, ok := r.SetString(fmt.Sprint(v)); !ok {
+			return 0, false
+		}
+	}
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() != 0 && new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(r.Sign())))
+	}
+	if !q.IsInt64() {
+		return 0, false
+	}
+	return q.Int64(), true
+}
+
+// formatMinor écrit un montant en unités mineures avec ses décimales et séparateurs.
+func formatMinor(minor int64, decimals int, decSep, thouSep string) string {
+	u := uint64(minor)
+	if minor < 0 {
+		u = -u // Exact même pour le plus petit int64
+	}
+	abs := strconv.FormatUint(u, 10)
+	if len(abs) <= decimals {
+		abs = strings.Repeat("0", decimals-len(abs)+1) + abs
+	}
+	intPart, frac := abs[:len(abs)-decimals], abs[len(abs)-decimals:]
+
+	var b strings.Builder
+	if minor < 0 {
+		b.WriteByte('-')
+	}
+	for i, ch := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(thouSep)
+		}
+		b.WriteRune(ch)
+	}
+	if decimals > 0 {
+		b.WriteString(decSep + frac)
+	}
+	return b.String()
+}
+
+// moneyAmount formate un montant sans symbole, selon la locale du champ : valeur des
+// champs de saisie et de l'export CSV.
+func moneyAmount(minor int64, m *entity.MoneyConfig) string {
+	if m.Locale == "en" {
+		return formatMinor(minor, m.Digits, ".", "")
+	}
+	return formatMinor(minor, m.Digits, ",", "")
+}
+
+// formatMoney formate un montant pour l'affichage : "1 846,67 €" en français (espaces
+// insécables), "€1,846.67" en anglais.
+func formatMoney(minor int64, m *entity.MoneyConfig) string {
+	if m.Locale == "en" {
+		s := formatMinor(minor, m.Digits, ".", ",")
+		if minor < 0 {
+			return "-" + m.Symbol() + s[1:]
+		}
+		return m.Symbol() + s
+	}
+	return formatMinor(minor, m.Digits, ",", " ") + " " + m.Symbol()
+}
+
+// displayMoney formate la valeur lue en base d'un champ money, ou la renvoie telle quelle
+// si elle n'est pas un montant.
+func displayMoney(raw interface{}, m *entity.MoneyConfig) interface{} {
+	if minor, ok := moneyValue(raw, m); ok {
+		return formatMoney(minor, m)
+	}
+	return raw
+}
+
+// checkMoneyValue vérifie un montant saisi et renvoie le message d'erreur, ou "" s'il est
+// valide (ou vide).
+func checkMoneyValue(f entity.Field, raw string) string {
+	if f.Money == nil || strings.TrimSpace(raw) == "" {
+		return ""
+	}
+	if _, err := parseMoney(raw, f.Money.Digits); err != nil {
+		return fmt.Sprintf("Montant « %s » invalide : %v", raw, err)
+	}
+	return ""
+}
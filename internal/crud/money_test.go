@@ -0,0 +1,162 @@
+// internal/crud/money_test.go
+package crud
+
+import (
+	"math"
+	"testing"
+
+	"example.com/go-crud/internal/entity"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in       string
+		decimals int
+		want     int64
+		wantErr  bool
+	}{
+		{"1846,67", 2, 184667, false},
+		{"1 846,67", 2, 184667, false},
+		{"1\u00a0846,67", 2, 184667, false}, // Espace insécable
+		{"1.846,67", 2, 184667, false},
+		{"1,846.67", 2, 184667, false},
+		{"1846.67", 2, 184667, false},
+		{"12,5", 2, 1250, false},
+		{"-12,5", 2, -1250, false},
+		{"+3", 2, 300, false},
+		{",5", 2, 50, false},
+		{"1.846.670", 2, 184667000, false}, // Milliers seulement
+		{"0,10", 2, 10, false},
+		{"1,230", 2, 123, false}, // Zéros au-delà des décimales de la devise
+		{"1500", 0, 1500, false},
+		{"12,345", 3, 12345, false},
+		{"  7 ", 2, 700, false},
+		{"0", 2, 0, false},
+		{"1,234", 2, 0, true}, // Trois décimales significatives
+		{"abc", 2, 0, true},
+		{"", 2, 0, true},
+		{"-", 2, 0, true},
+		{"1e5", 2, 0, true},
+		{"99999999999999999999", 2, 0, true},
+	}
+	for _, tc := range tests {
+		got, err := parseMoney(tc.in, tc.decimals)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("parseMoney(%q, %d) = %d, %v ; attendu %d, erreur %v", tc.in, tc.decimals, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	eur := &entity.MoneyConfig{Currency: "EUR", Digits: 2}
+	usd := &entity.MoneyConfig{Currency: "USD", Digits: 2, Locale: "en"}
+	jpy := &entity.MoneyConfig{Currency: "JPY", Digits: 0}
+	tests := []struct {
+		minor  int64
+		m      *entity.MoneyConfig
+		want   string
+		amount string // moneyAmount
+	}{
+		{184667, eur, "1\u00a0846,67\u00a0" + eur.Symbol(), "1846,67"},
+		{-1250, eur, "-12,50\u00a0" + eur.Symbol(), "-12,50"},
+		{5, eur, "0,05\u00a0" + eur.Symbol(), "0,05"},
+		{0, eur, "0,00\u00a0" + eur.Symbol(), "0,00"},
+		{123456789, usd, usd.Symbol() + "1,234,567.89", "1234567.89"},
+		{-184667, usd, "-" + usd.Symbol() + "1,846.67", "-1846.67"},
+		{1500, jpy, "1\u00a0500\u00a0" + jpy.Symbol(), "1500"},
+	}
+	for _, tc := range tests {
+		if got := formatMoney(tc.minor, tc.m); got != tc.want {
+			t.Errorf("formatMoney(%d, %s) = %q, attendu %q", tc.minor, tc.m.Currency, got, tc.want)
+		}
+		if got := moneyAmount(tc.minor, tc.m); got != tc.amount {
+			t.Errorf("moneyAmount(%d, %s) = %q, attendu %q", tc.minor, tc.m.Currency, got, tc.amount)
+		}
+		// Le montant saisi relu donne les mêmes unités mineures
+		if back, err := parseMoney(tc.amount, tc.m.Digits); err != nil || back != tc.minor {
+			t.Errorf("parseMoney(moneyAmount(%d)) = %d, %v", tc.minor, back, err)
+		}
+	}
+	if got := formatMinor(math.MinInt64, 2, ",", ""); got != "-92233720368547758,08" {
+		t.Errorf("formatMinor(MinInt64) = %q", got)
+	}
+}
+
+func TestMoneyValue(t *testing.T) {
+	decimal := &entity.MoneyConfig{Currency: "EUR", Digits: 2}
+	minor := &entity.MoneyConfig{Currency: "EUR", Digits: 2, Storage: "minor"}
+	tests := []struct {
+		raw    interface{}
+		m      *entity.MoneyConfig
+		want   int64
+		wantOK bool
+	}{
+		{1846.67, decimal, 184667, true}, // Colonne REAL : pas d'erreur d'arrondi
+		{0.1, decimal, 10, true},
+		{"1846.67", decimal, 184667, true},
+		{[]byte("-12.50"), decimal, -1250, true},
+		{int64(18), decimal, 1800, true},
+		{int64(184667), minor, 184667, true},
+		{nil, decimal, 0, false},
+		{"n/a", decimal, 0, false},
+		{"12.5", minor, 0, false}, // Des unités mineures sont entières
+	}
+	for _, tc := range tests {
+		got, ok := moneyValue(tc.raw, tc.m)
+		if ok != tc.wantOK || got != tc.want {
+			t.Errorf("moneyValue(%#v, %s) = %d, %v ; attendu %d, %v", tc.raw, tc.m.Storage, got, ok, tc.want, tc.wantOK)
+		}
+	}
+	if got := storeMoney(184667, decimal); got != "1846.67" {
+		t.Errorf("storeMoney décimal = %#v", got)
+	}
+	if got := storeMoney(184667, minor); got != int64(184667) {
+		t.Errorf("storeMoney minor = %#v", got)
+	}
+}
+
+func TestRoundMinor(t *testing.T) {
+	tests := []struct {
+		raw    interface{}
+		want   int64
+		wantOK bool
+	}{
+		{int64(42), 42, true},
+		{12.5, 13, true},
+		{-12.5, -13, true}, // Au plus proche, en s'éloignant de zéro
+		{12.49, 12, true},
+		{[]byte("184667.0000"), 184667, true},
+		{"-0.5", -1, true},
+		{"1/3", 0, true},
+		{nil, 0, false},
+		{"abc", 0, false},
+		{"1e30", 0, false}, // Hors int64
+	}
+	for _, tc := range tests {
+		got, ok := roundMinor(tc.raw)
+		if ok != tc.wantOK || got != tc.want {
+			t.Errorf("roundMinor(%#v) = %d, %v ; attendu %d, %v", tc.raw, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestCheckMoneyValue(t *testing.T) {
+	f := entity.Field{Name: "solde", Money: &entity.MoneyConfig{Currency: "EUR", Digits: 2}}
+	tests := []struct {
+		field entity.Field
+		raw   string
+		ok    bool
+	}{
+		{f, "1 846,67", true},
+		{f, "", true},
+		{f, "  ", true},
+		{f, "12,345", false},
+		{f, "douze", false},
+		{entity.Field{Name: "libelle"}, "douze", true}, // Pas un champ money
+	}
+	for _, tc := range tests {
+		if msg := checkMoneyValue(tc.field, tc.raw); (msg == "") != tc.ok {
+			t.Errorf("checkMoneyValue(%s, %q) = %q", tc.field.Name, tc.raw, msg)
+		}
+	}
+}
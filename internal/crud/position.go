@@ -0,0 +1,131 @@
+// internal/crud/position.go
+package crud
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// filteredQuery renvoie la requête de la liste restreinte par la recherche et les filtres,
+// sans tri ni pagination.
+func (h *crudHandler) filteredQuery(search string, filters []listFilter) *gorm.DB {
+	q := h.db.Table(h.ec.Table)
+	if search != "" {
+		if where, args, ok := h.searchCondition(search); ok {
+			q = q.Where(where, args...)
+		}
+	}
+	return applyFilters(q, filters)
+}
+
+// listOrder renvoie le tri de la liste paginée par décalage : champ de regroupement, champ de
+// tri, puis clé primaire pour départager les égalités (ordre stable d'une page à l'autre).
+func (h *crudHandler) listOrder(groupField, sortField, sortOrder string) string {
+	order := sortField + " " + sortOrder
+	if groupField != "" {
+		order = groupField + " ASC, " + order
+	}
+	if len(h.ec.PrimaryKey) > 1 || sortField != h.ec.PrimaryKey[0] {
+		order += ", " + keyOrder(h.ec, "ASC")
+	}
+	return order
+}
+
+// highlightURL renvoie l'URL de la liste qui affiche et surligne l'enregistrement de clé id, avec le
+// tri, la recherche, les filtres et la taille de page transmis par le formulaire. Si
+// l'enregistrement ne correspond pas à la recherche ou aux filtres, ils sont retirés.
+func (h *crudHandler) highlightURL(c *gin.Context, id string) string {
+	q := c.Request.URL.Query()
+	for _, k := range []string{"page", "after", "before", "highlight", "copy", "error"} {
+		q.Del(k)
+	}
+	sortField, sortOrder := h.sortColumn(c)
+	pageSize := h.ec.List.PageSize
+	if v, err := strconv.Atoi(c.Query("pageSize")); err == nil && v > 0 {
+		pageSize = v
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	q.Set("sort", sortField)
+	q.Set("order", sortOrder)
+	q.Set("pageSize", strconv.Itoa(pageSize))
+	q.Set("highlight", id)
+
+	search := strings.TrimSpace(c.Query("search"))
+	filters := h.parseFilters(c)
+	var n int64
+	if where, args, err := keyCondition(h.ec, h.ec.Table+".", id); err == nil {
+		h.filteredQuery(search, filters).Where(where, args...).Count(&n)
+	}
+	if n == 0 {
+		search, filters = "", nil
+		for k := range q {
+			if k == "search" || strings.HasPrefix(k, "f_") {
+				q.Del(k)
+			}
+		}
+	}
+
+	base := h.filteredQuery(search, filters)
+	if h.keyset() {
+		if after, ok := h.cursorBefore(base, sortField, sortOrder, id); ok {
+			q.Set("after", after)
+		}
+	} else {
+		q.Set("page", strconv.Itoa(h.pageOf(base, h.groupField(c), sortField, sortOrder, id, pageSize)))
+	}
+	return "/" + h.ec.List.Name + "?" + q.Encode()
+}
+
+// pageOf renvoie la page (pagination par décalage) où se trouve l'enregistrement id : son rang
+// est calculé par ROW_NUMBER() sur exactement le même tri que la liste.
+func (h *crudHandler) pageOf(base *gorm.DB, groupField, sortField, sortOrder, id string, pageSize int) int {
+	ranked := base.Select(strings.Join(h.ec.PrimaryKey, ", ") + ", ROW_NUMBER() OVER (ORDER BY " + h.listOrder(groupField, sortField, sortOrder) + ") AS rn")
+	where, args, err := keyCondition(h.ec, "", id)
+	var rn int64
+	if err == nil {
+		err = h.db.Table("(?) AS ranked", ranked).Select("rn").Where(where, args...).Row().Scan(&rn)
+	}
+	if err != nil || rn < 1 {
+		log.Printf("[HIGHLIGHT] Rang de %s/%s introuvable : %v", h.ec.Name, id, err)
+		return 1
+	}
+	return int((rn-1)/int64(pageSize)) + 1
+}
+
+// cursorBefore renvoie le curseur de la ligne qui précède l'enregistrement id (pagination par
+// curseur) : la page "after" de ce curseur commence par l'enregistrement. Faux s'il est en tête.
+func (h *crudHandler) cursorBefore(base *gorm.DB, sortField, sortOrder, id string) (string, bool) {
+	key := h.ec.PrimaryKey[0]
+	row, err := whereKey(base.Session(&gorm.Session{}), h.ec, id)
+	if err != nil {
+		return "", false
+	}
+	target := make(map[string]interface{})
+	if err := row.Select("*").Take(&target).Error; err != nil {
+		return "", false
+	}
+	// Même valeur de comparaison que celle relue d'un curseur par keysetPage
+	value := h.sortValue(sortField, cursorValue(target[sortField]))
+	// Lignes qui précèdent : parcours dans l'ordre inverse de la liste
+	asc := sortOrder == "asc"
+	dir := "ASC"
+	if asc {
+		dir = "DESC"
+	}
+	where, args := keysetAfter(sortField, key, !asc, value, cursorValue(target[key]))
+	q := base.Where(where, args...)
+	if sortField != key {
+		q = q.Order(sortField + " " + nullsFirst(q, dir))
+	}
+	var prev []map[string]interface{}
+	if err := q.Order(key + " " + dir).Limit(1).Find(&prev).Error; err != nil || len(prev) == 0 {
+		return "", false
+	}
+	return h.encodeCursor(prev[0], sortField), true
+}
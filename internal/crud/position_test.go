@@ -0,0 +1,72 @@
+// internal/crud/position_test.go
+package crud
+
+import (
+	"testing"
+	"time"
+
+	"example.com/go-crud/internal/entity"
+)
+
+// TestCursorBefore vérifie que la page "after" du curseur calculé pour une ligne surlignée
+// commence par cette ligne, sur une colonne DATETIME avec doublons et valeurs NULL.
+func TestCursorBefore(t *testing.T) {
+	db := openDialect(t, "sqlite")
+	db.Exec("CREATE TABLE pos (id INTEGER PRIMARY KEY, maj DATETIME)")
+	day := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	for i, maj := range []interface{}{day, nil, day.Add(time.Hour), day, day.Add(-time.Hour)} {
+		if err := db.Exec("INSERT INTO pos (id, maj) VALUES (?, ?)", i+1, maj).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &crudHandler{db: db, ec: &entity.EntityConfig{
+		Table:      "pos",
+		PrimaryKey: []string{"id"},
+		FieldsByName: map[string]entity.Field{
+			"id":  {Name: "id", Type: "int"},
+			"maj": {Name: "maj", Type: "datetime"},
+		},
+	}}
+	// Ordre croissant : 2 (NULL), 5, 1, 4, 3 ; décroissant : 3, 4, 1, 5, 2
+	tests := []struct {
+		order  string
+		id     string
+		first  bool  // Ligne en tête : pas de curseur
+		wantID int64 // Première ligne de la page "after" du curseur
+	}{
+		{"asc", "2", true, 0},
+		{"asc", "5", false, 5},
+		{"asc", "4", false, 4}, // Même date que la ligne 1 qui la précède
+		{"asc", "3", false, 3},
+		{"desc", "3", true, 0},
+		{"desc", "1", false, 1},
+		{"desc", "2", false, 2},
+	}
+	for _, tc := range tests {
+		cursor, ok := h.cursorBefore(db.Table("pos"), "maj", tc.order, tc.id)
+		if ok == tc.first {
+			t.Errorf("cursorBefore(%s, %s) = %q, %v", tc.order, tc.id, cursor, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		value, id, err := decodeCursor(cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		asc := tc.order == "asc"
+		dir := "DESC"
+		if asc {
+			dir = "ASC"
+		}
+		where, args := keysetAfter("maj", "id", asc, h.sortValue("maj", value), id)
+		var ids []int64
+		if err := db.Table("pos").Where(where, args...).Order("maj "+nullsFirst(db, dir)+", id "+dir).Limit(1).Pluck("id", &ids).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(ids) != 1 || ids[0] != tc.wantID {
+			t.Errorf("cursorBefore(%s, %s) : page suivante %v, attendu [%d]", tc.order, tc.id, ids, tc.wantID)
+		}
+	}
+}
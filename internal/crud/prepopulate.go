@@ -0,0 +1,109 @@
+// internal/crud/prepopulate.go
+package crud
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"example.com/go-crud/config/form_codes"
+	"github.com/gin-gonic/gin"
+)
+
+// newRecordValues construit les valeurs initiales d'une fiche en création :
+// valeurs par défaut des champs, puis règles `prepopulate` du form_code,
+// puis copie d'un enregistrement (?copy=<id>), chaque étape pouvant écraser la précédente.
+func (h *crudHandler) newRecordValues(c *gin.Context) (map[string]interface{}, error) {
+	dataRow := make(map[string]interface{})
+
+	// 1) Valeurs par défaut déclarées dans la liste des champs de l'entité
+	for _, f := range h.ec.Fields {
+		if f.Default != nil && !h.ec.IsPrimaryKey(f.Name) {
+			dataRow[f.Name] = f.Default
+		}
+	}
+
+	// 2) Règles de pré-remplissage du form_code
+	if h.ec.Code != nil {
+		for field, spec := range h.ec.Code.Prepopulate {
+			if v, ok := h.prepopulateValue(c, field, spec); ok {
+				dataRow[field] = v
+			}
+		}
+	}
+
+	// 3) Copie d'un enregistrement existant
+	if copyID := c.Query("copy"); copyID != "" {
+		if err := h.copyRecord(copyID, dataRow); err != nil {
+			return nil, err
+		}
+	}
+	return dataRow, nil
+}
+
+// copyRecord recopie dans dataRow les champs d'un enregistrement existant, hors clé primaire,
+// champs calculés (readonly au niveau de l'entité), pièces jointes (le fichier appartient à
+// l'enregistrement copié) et champs listés dans fiche.duplicateExclude.
+func (h *crudHandler) copyRecord(id string, dataRow map[string]interface{}) error {
+	q, err := whereKey(h.db.Table(h.ec.Table), h.ec, id)
+	if err != nil {
+		return err
+	}
+	source := make(map[string]interface{})
+	if err := q.Select("*").Take(&source).Error; err != nil {
+		return err
+	}
+	h.formatForForm(source)
+	excluded := make(map[string]bool, len(h.ec.Fiche.DuplicateExclude))
+	for _, name := range h.ec.Fiche.DuplicateExclude {
+		excluded[name] = true
+	}
+	for _, f := range h.ec.Fields {
+		if h.ec.IsPrimaryKey(f.Name) || f.ReadOnly || f.Attachment != nil || excluded[f.Name] {
+			continue
+		}
+		if v, ok := source[f.Name]; ok && v != nil {
+			dataRow[f.Name] = v
+		}
+	}
+	return nil
+}
+
+// prepopulateValue calcule la valeur de pré-remplissage d'un champ selon le type de règle :
+// "now" (date courante au format donné), "literal" (valeur fixe), "param" (paramètre de l'URL),
+// "user" (utilisateur courant) ou "sql" (première colonne de la première ligne d'une requête).
+func (h *crudHandler) prepopulateValue(c *gin.Context, field string, spec form_codes.PrepopulateSpec) (interface{}, bool) {
+	switch spec.Type {
+	case "now":
+		format := spec.Format
+		if format == "" {
+			format = "2006-01-02 15:04:05"
+		}
+		return time.Now().Format(format), true
+	case "literal":
+		return spec.Value, true
+	case "param":
+		param := spec.Param
+		if param == "" {
+			param = field
+		}
+		v, ok := c.GetQuery(param)
+		return v, ok
+	case "user":
+		user := currentUser(c)
+		return user, user != ""
+	case "sql":
+		var v interface{}
+		if err := h.db.Raw(spec.SQL).Row().Scan(&v); err != nil {
+			log.Printf("[PREPOPULATE] Erreur SQL pour %s.%s : %v", h.ec.Name, field, err)
+			return nil, false
+		}
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		return fmt.Sprint(v), v != nil
+	default:
+		log.Printf("[PREPOPULATE] Type inconnu « %s » pour %s.%s", spec.Type, h.ec.Name, field)
+		return nil, false
+	}
+}
@@ -4,12 +4,16 @@ package crud
 import (
 	"database/sql"
 	"fmt"
+	"html/template"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"example.com/go-crud/config/form_codes"
 	"example.com/go-crud/internal/entity"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -17,13 +21,18 @@ import (
 
 // crudHandler détient les dépendances (DB, config d'entité) pour nos handlers.
 type crudHandler struct {
-	db *gorm.DB
-	ec *entity.EntityConfig
+	db  *gorm.DB
+	ec  *entity.EntityConfig
+	fts bool // Index plein texte FTS5 disponible pour l'entité
 }
 
 // RegisterEntity configure les routes CRUD pour une entité en utilisant le crudHandler.
 func RegisterEntity(r *gin.Engine, db *gorm.DB, ec *entity.EntityConfig) {
 	h := &crudHandler{db: db, ec: ec}
+	h.fts = setupFullText(db, ec)
+	searchHandlers = append(searchHandlers, h)
+	registered = append(registered, ec)
+	h.checkDataSources()
 
 	// Routes standard (liste, fiche)
 	r.GET("/"+ec.List.Name, h.list)
@@ -35,6 +44,33 @@ func RegisterEntity(r *gin.Engine, db *gorm.DB, ec *entity.EntityConfig) {
 	r.POST("/"+ec.Fiche.Name+"/delete/:id", h.delete)
 	r.GET("/"+ec.Fiche.Name+"/vision-data/:field", h.visionData)
 
+	// Verrouillage pendant la modification (entity.lock) : annulation ou déverrouillage forcé
+	if ec.Lock != nil {
+		r.POST("/"+ec.Fiche.Name+"/unlock/:id", h.unlock)
+	}
+
+	// Pièces jointes (champs file et image) : téléchargement et vignettes
+	if ec.HasAttachments() {
+		r.GET("/"+ec.Fiche.Name+"/attachment/:att", h.downloadAttachment)
+		r.GET("/"+ec.Fiche.Name+"/attachment/:att/thumbnail", h.attachmentThumbnail)
+	}
+
+	// Historique des versions (entity.history)
+	if ec.History {
+		r.GET("/"+ec.Fiche.Name+"/history/:id", h.history)
+		r.POST("/"+ec.Fiche.Name+"/history/:id/revert/:version", h.revertVersion)
+	}
+
+	// Actions groupées sur la sélection de la liste
+	r.POST("/"+ec.List.Name+"/bulk/delete", h.bulkDelete)
+	r.POST("/"+ec.List.Name+"/bulk/update", h.bulkUpdate)
+	r.POST("/"+ec.List.Name+"/bulk/export", h.bulkExport)
+
+	// Vues enregistrées de la liste
+	r.POST("/"+ec.List.Name+"/views", h.saveView)
+	r.POST("/"+ec.List.Name+"/views/delete/:id", h.deleteView)
+	r.POST("/"+ec.List.Name+"/columns", h.saveColumns)
+
 	// NOUVEAU : Enregistrer les routes pour les formulaires 'vision'
 	for name := range ec.VisionForms {
 		r.GET("/vision/"+name, h.vision)
@@ -76,8 +112,22 @@ func (h *crudHandler) vision(c *gin.Context) {
 	sortField := c.DefaultQuery("sort", visionCfg.DefaultSortField)
 	sortOrder := c.DefaultQuery("order", visionCfg.DefaultSortOrder)
 
+	// Recherche plein texte : la requête de la vision est restreinte aux identifiants trouvés
+	search := strings.TrimSpace(c.Query("search"))
+	// (l'index FTS de l'entité n'est utilisable que si la vision interroge la même base)
+	db := h.source(visionCfg.DataSource)
+	visionSearch := h.fts && db == h.db && slices.Contains(visionCfg.Columns, h.ec.PrimaryKey[0])
+	if match := ftsMatch(search); visionSearch && match != "" {
+		fts := ftsTable(h.ec)
+		visionCfg.SQL = fmt.Sprintf("SELECT * FROM (%s) AS vision_rows WHERE %s IN (SELECT rowid FROM %s WHERE %s MATCH :fts_search)",
+			strings.TrimRight(strings.TrimSpace(visionCfg.SQL), ";"), h.ec.PrimaryKey[0], fts, fts)
+		args = append(args, sql.Named("fts_search", match))
+	}
+
+	visionCfg.SQL = namedParams(db, visionCfg.SQL)
+
 	var data []map[string]interface{}
-	query := h.db.Raw(visionCfg.SQL, args...)
+	query := db.Raw(visionCfg.SQL, args...)
 	err := query.Scan(&data).Error
 
 	if err != nil {
@@ -86,6 +136,21 @@ func (h *crudHandler) vision(c *gin.Context) {
 		log.Printf("[VISION] Requête pour '%s' a retourné %d enregistrements", visionName, len(data))
 	}
 
+	// Liens d'édition : clé encodée des lignes qui portent toutes les colonnes de la clé primaire
+	for _, row := range data {
+		complete := true
+		for _, col := range h.ec.PrimaryKey {
+			if _, ok := row[col]; !ok {
+				complete = false
+			}
+		}
+		if complete {
+			row["_key"] = rowKey(h.ec, row)
+		}
+	}
+
+	h.loadAttachments(data...)
+
 	// V29 - Logique pour le mode sélectionnable
 	allowSelectable := true // Par défaut, la sélection est autorisée
 	if visionCfg.Actions.AllowSelectable != nil {
@@ -102,69 +167,165 @@ func (h *crudHandler) vision(c *gin.Context) {
 		"ReturnTo":        returnTo,
 		"AllowSelectable": allowSelectable, // On passe la valeur au template
 		"Columns":         visionCfg.Columns,
+		"ColumnWidths":    h.ec.List.ColumnWidths,
+		"Footer":          h.visionFooter(visionCfg, args),
 		"Data":            data,
 		"Page":            page,
 		"PageSize":        pageSize,
 		"PageSizeOptions": visionCfg.PageSizeOptions,
 		"SortField":       sortField,
 		"SortOrder":       sortOrder,
-		"Search":          "",
+		"Search":          search,
+		"VisionSearch":    visionSearch,
+		"VisionParams":    visionParams(c),
 		"Total":           len(data),
 		"TotalPages":      1,
 	})
 }
 
-
 // list gère l'affichage de la liste paginée, triée et filtrée.
 func (h *crudHandler) list(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
 	pageSize := h.ec.List.PageSize
 	if ps := c.Query("pageSize"); ps != "" {
-		if v, err := strconv.Atoi(ps); err == nil {
+		if v, err := strconv.Atoi(ps); err == nil && v > 0 {
 			pageSize = v
 		}
 	}
 
-	sortField := c.DefaultQuery("sort", h.ec.List.DefaultSortField)
-	sortOrder := c.DefaultQuery("order", h.ec.List.DefaultSortOrder)
+	sortField, sortOrder := h.sortColumn(c)
 
 	search := strings.TrimSpace(c.Query("search"))
-	highlightID, _ := strconv.Atoi(c.Query("highlight"))
-
-	query := h.db.Table(h.ec.Table).Select(h.ec.List.Columns)
-	countQ := h.db.Table(h.ec.Table)
+	highlight := c.Query("highlight")
+
+	// Colonnes affichées : celles de la vue, sinon la préférence de l'utilisateur, sinon le YAML.
+	// La clé primaire est toujours lue pour les liens d'édition.
+	columns, columnWidths, customColumns := h.listColumns(c)
+	selectCols := columns
+	for _, col := range h.ec.PrimaryKey {
+		if !slices.Contains(selectCols, col) {
+			selectCols = append([]string{col}, selectCols...)
+		}
+	}
+	// Regroupement : les lignes sont triées d'abord par le champ de regroupement
+	// (non disponible en pagination par curseur)
+	groupField := ""
+	if !h.keyset() {
+		groupField = h.groupField(c)
+	}
+	if groupField != "" && !slices.Contains(selectCols, groupField) {
+		selectCols = append(slices.Clone(selectCols), groupField)
+	}
+	// Pagination par curseur : le champ de tri doit être lu pour construire le curseur
+	if h.keyset() && !slices.Contains(selectCols, sortField) {
+		selectCols = append(slices.Clone(selectCols), sortField)
+	}
 
-	if search != "" && len(h.ec.List.SearchableFields) > 0 {
-		var conds []string
-		var args []interface{}
-		for _, f := range h.ec.List.SearchableFields {
-			conds = append(conds, f+" LIKE ?")
-			args = append(args, "%"+search+"%")
+	// Recherche et filtres par colonne, appliqués à l'identique à la requête de comptage
+	filters := h.parseFilters(c)
+	query := h.filteredQuery(search, filters).Select(selectCols)
+	countQ := h.filteredQuery(search, filters)
+
+	if search != "" {
+		// Sans tri choisi, les résultats plein texte sont classés par pertinence
+		// (sauf en pagination par curseur, qui repose sur le champ de tri)
+		if _, sorted := c.GetQuery("sort"); !sorted && !h.keyset() {
+			if rank, ok := h.searchRank(search); ok {
+				query = query.Order(rank)
+			}
 		}
-		where := strings.Join(conds, " OR ")
-		query = query.Where(where, args...)
-		countQ = countQ.Where(where, args...)
 	}
 
 	var data []map[string]interface{}
-	query.Order(sortField + " " + sortOrder).
-		Offset((page - 1) * pageSize).
-		Limit(pageSize).
-		Find(&data)
+	var pg listPage
+	if h.keyset() {
+		var err error
+		data, pg, err = h.keysetPage(c, query, sortField, sortOrder, pageSize)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Pagination : %v", err)
+			return
+		}
+		// Comptage approximatif, seulement sans recherche ni filtre
+		if h.ec.List.ApproximateCount && search == "" && filterQuery(filters) == "" {
+			if n, ok := h.approximateCount(); ok {
+				pg.Total, pg.Approximate = &n, true
+			}
+		}
+	} else {
+		query.Order(h.listOrder(groupField, sortField, sortOrder)).
+			Offset((page - 1) * pageSize).
+			Limit(pageSize).
+			Find(&data)
+
+		var total int64
+		countQ.Session(&gorm.Session{}).Count(&total)
+		pg = listPage{Mode: "offset", Page: page, PageSize: pageSize, Total: &total,
+			TotalPages: int((total + int64(pageSize) - 1) / int64(pageSize))}
+	}
 
-	// Gestion du surlignage
-	for _, row := range data {
-		if idVal, ok := row["id"]; ok {
-			if idInt, err := strconv.Atoi(fmt.Sprint(idVal)); err == nil && idInt == highlightID {
-				row["_highlight"] = true
+	// API JSON : lignes brutes et pagination. Les montants sont écrits en texte décimal
+	// ("1846.67"), jamais en flottant, quel que soit leur stockage.
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		for _, row := range data {
+			for k, v := range row {
+				if b, ok := v.([]byte); ok {
+					row[k] = string(b)
+				}
+				if f, ok := h.ec.FieldsByName[k]; ok && f.Money != nil {
+					if minor, ok := moneyValue(row[k], f.Money); ok {
+						row[k] = formatMinor(minor, f.Money.Digits, ".", "")
+					}
+				}
 			}
 		}
+		c.JSON(http.StatusOK, gin.H{"data": data, "pagination": pg})
+		return
 	}
 
-	// Formater les nombres pour l'affichage
+	// Valeur brute du groupe de chaque ligne, avant le formatage des nombres
+	var previousGroup interface{}
+	hasPreviousGroup := false
+	if groupField != "" {
+		for _, row := range data {
+			row["_group_key"] = row[groupField]
+			if b, ok := row[groupField].([]byte); ok {
+				row["_group_key"] = string(b)
+			}
+		}
+		// Groupe de la dernière ligne de la page précédente, pour signaler un groupe commencé plus tôt
+		if offset := (page - 1) * pageSize; offset > 0 {
+			err := countQ.Session(&gorm.Session{}).Select(groupField).
+				Order(h.listOrder(groupField, sortField, sortOrder)).
+				Offset(offset - 1).Limit(1).Row().Scan(&previousGroup)
+			hasPreviousGroup = err == nil
+			if b, ok := previousGroup.([]byte); ok {
+				previousGroup = string(b)
+			}
+		}
+	}
+
+	// Clé encodée de chaque ligne (liens d'édition, suppression, sélection), valeur de la clé
+	// renvoyée au champ appelant par une fenêtre vision (_key_value), et surlignage
+	for _, row := range data {
+		row["_key"] = rowKey(h.ec, row)
+		row["_key_value"] = row["_key"]
+		if len(h.ec.PrimaryKey) == 1 {
+			row["_key_value"] = keyPart(row[h.ec.PrimaryKey[0]])
+		}
+		if highlight != "" && row["_key"] == highlight {
+			row["_highlight"] = true
+		}
+	}
+
+	// Formater les nombres et les montants pour l'affichage
 	for _, row := range data {
 		for _, f := range h.ec.Fields {
-			if f.Type == "number" {
+			if f.Money != nil {
+				row[f.Name] = displayMoney(row[f.Name], f.Money)
+			} else if f.Type == "number" {
 				if ficheDef, ok := h.ec.FicheFieldsByName[f.Name]; ok && ficheDef.DecimalSeparator != "" {
 					if raw, ok := row[f.Name]; ok && raw != nil {
 						if num, err := strconv.ParseFloat(fmt.Sprint(raw), 64); err == nil {
@@ -176,20 +337,29 @@ func (h *crudHandler) list(c *gin.Context) {
 		}
 	}
 
-	var total int64
-	countQ.Count(&total)
+	h.loadAttachments(data...)
 
-	// Correction du bug de division par zéro (Force Write)
-	var totalPages int
-	if pageSize > 0 {
-		totalPages = int((total + int64(pageSize) - 1) / int64(pageSize))
-	} else {
-		totalPages = 0 // Évite le plantage si pageSize est 0
+	if groupField != "" {
+		h.markGroups(countQ, groupField, columns, data, previousGroup, hasPreviousGroup)
+	}
+
+	footer := h.listFooter(countQ)
+
+	views, viewTitle := h.listViews(c)
+	var groupOptions []map[string]string
+	if !h.keyset() {
+		groupOptions = h.groupOptions()
 	}
 
 	c.HTML(http.StatusOK, "index.html", gin.H{
 		"Entity":          h.ec,
-		"Columns":         h.ec.List.Columns,
+		"Columns":         columns,
+		"ColumnWidths":    columnWidths,
+		"ColumnChoices":   h.columnChoices(columns, columnWidths),
+		"CustomColumns":   customColumns,
+		"Footer":          footer,
+		"GroupField":      groupField,
+		"GroupOptions":    groupOptions,
 		"Data":            data,
 		"Page":            page,
 		"PageSize":        pageSize,
@@ -197,20 +367,38 @@ func (h *crudHandler) list(c *gin.Context) {
 		"SortField":       sortField,
 		"SortOrder":       sortOrder,
 		"Search":          search,
-		"Total":           total,
-		"TotalPages":      totalPages,
+		"Filters":         filters,
+		"FilterQuery":     template.URL(listExtraQuery(c, filters)),
+		"Cols":            c.Query("cols"),
+		"Views":           views,
+		"View":            c.Query("view"),
+		"ViewTitle":       viewTitle,
+		"ViewQuery":       currentListQuery(c),
+		"Total":           pg.Total,
+		"TotalPages":      pg.TotalPages,
+		"Pagination":      pg,
+		"Error":           c.Query("error"),
 	})
 }
 
+// visionParams renvoie les paramètres de contexte de la vision (hors recherche),
+// à reconduire dans le formulaire de recherche.
+func visionParams(c *gin.Context) map[string]string {
+	params := make(map[string]string)
+	for k, v := range c.Request.URL.Query() {
+		if k != "search" && len(v) > 0 {
+			params[k] = v[0]
+		}
+	}
+	return params
+}
+
 // newForm affiche le formulaire de création.
 func (h *crudHandler) newForm(c *gin.Context) {
-	dataRow := make(map[string]interface{})
-	if h.ec.Code != nil {
-		for field, spec := range h.ec.Code.Prepopulate {
-			if spec.Type == "now" {
-				dataRow[field] = time.Now().Format(spec.Format)
-			}
-		}
+	dataRow, err := h.newRecordValues(c)
+	if err != nil {
+		c.String(http.StatusNotFound, "Enregistrement à copier non trouvé : %v", err)
+		return
 	}
 
 	c.HTML(http.StatusOK, "form.html", gin.H{
@@ -220,10 +408,14 @@ func (h *crudHandler) newForm(c *gin.Context) {
 		"DataRow":   dataRow,
 		"Errors":    map[string]string{},
 		"ComboData": h.prepareComboData(),
+		"SubForms":  h.subForms(""),
 		"Page":      c.Query("page"),
 		"PageSize":  c.Query("pageSize"),
 		"SortField": c.Query("sort"),
 		"SortOrder": c.Query("order"),
+		// Recherche et filtres de la liste d'origine, retransmis par les liens de la fiche
+		"Search":      c.Query("search"),
+		"FilterQuery": template.URL(listExtraQuery(c, h.parseFilters(c))),
 	})
 }
 
@@ -235,38 +427,83 @@ func (h *crudHandler) create(c *gin.Context) {
 		return
 	}
 
-	vals := h.bindAndConvertForm(c)
-
-	if err := h.db.Table(h.ec.Table).Create(vals).Error; err != nil {
-		c.String(http.StatusBadRequest, "Erreur de création : %v", err)
+	vals, err := h.bindAndConvertForm(c)
+	if err != nil {
+		h.saveFailed(c, "new", "Erreur de création", err)
+		return
+	}
+	subForms := h.postedSubForms(c)
+	user := currentUser(c)
+
+	// Contraintes, insertion, lignes des sous-fiches, hooks et journal dans une même
+	// transaction : la moindre erreur annule l'ensemble.
+	var newID string
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if newID, err = h.insertRecord(tx, user, vals); err != nil {
+			return err
+		}
+		return h.saveSubForms(tx, user, newID, subForms)
+	})
+	if err != nil {
+		h.saveFailed(c, "new", "Erreur de création", err)
 		return
 	}
 
-	// Redirection vers la bonne page avec surlignage
-	var newID int
-	h.db.Table(h.ec.Table).Select("id").Order("id DESC").Limit(1).Scan(&newID)
-	var countBefore int64
-	h.db.Table(h.ec.Table).Where("id <= ?", newID).Count(&countBefore)
-	page := int((countBefore + int64(h.ec.List.PageSize) - 1) / int64(h.ec.List.PageSize))
-
-	redirectURL := fmt.Sprintf(
-		"/%s?page=%d&pageSize=%d&sort=%s&order=%s&highlight=%d",
-		h.ec.List.Name, page, h.ec.List.PageSize, h.ec.List.DefaultSortField, h.ec.List.DefaultSortOrder, newID,
-	)
-	c.Redirect(http.StatusSeeOther, redirectURL)
+	// Redirection vers la page de la liste (tri, recherche et filtres de l'utilisateur)
+	// qui contient l'enregistrement créé, surligné
+	c.Redirect(http.StatusSeeOther, h.highlightURL(c, newID))
 }
 
 // editForm affiche le formulaire de modification avec les données pré-remplies.
 func (h *crudHandler) editForm(c *gin.Context) {
 	id := c.Param("id")
+	q, err := whereKey(h.db.Table(h.ec.Table), h.ec, id)
+	if err != nil {
+		c.String(http.StatusBadRequest, "%v", err)
+		return
+	}
 	dataRow := make(map[string]interface{})
 	// On sélectionne toutes les colonnes
-	if err := h.db.Table(h.ec.Table).Select("*").Where("id = ?", id).Take(&dataRow).Error; err != nil {
+	if err := q.Select("*").Take(&dataRow).Error; err != nil {
 		c.String(http.StatusNotFound, "Enregistrement non trouvé : %v", err)
 		return
 	}
 
-	// Formater les dates et les nombres pour l'affichage dans le formulaire
+	// Verrou de modification : la fiche est en lecture seule si un autre utilisateur le détient
+	lock, err := h.lockView(c, id)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Erreur de verrouillage : %v", err)
+		return
+	}
+
+	h.formatForForm(dataRow)
+	h.loadAttachments(dataRow)
+	dataRow["_key"] = id
+
+	c.HTML(http.StatusOK, "form.html", gin.H{
+		"Entity":      h.ec,
+		"Code":        h.ec.Code,
+		"Mode":        "edit",
+		"DataRow":     dataRow,
+		"Lock":        lock,
+		"ReadOnly":    lock["Owner"] != nil,
+		"Errors":      map[string]string{},
+		"ComboData":   h.prepareComboData(),
+		"SubForms":    h.subForms(id),
+		"History":     h.recentHistory(id),
+		"ListQuery":   template.URL(historyListQuery(c)),
+		"Page":        c.Query("page"),
+		"PageSize":    c.Query("pageSize"),
+		"SortField":   c.Query("sort"),
+		"SortOrder":   c.Query("order"),
+		"Search":      c.Query("search"),
+		"FilterQuery": template.URL(listExtraQuery(c, h.parseFilters(c))),
+	})
+}
+
+// formatForForm formate les dates, nombres et booléens d'un enregistrement pour l'affichage dans la fiche.
+func (h *crudHandler) formatForForm(dataRow map[string]interface{}) {
 	const dbFormat = "2006-01-02 15:04:05" // Format de stockage
 
 	for _, f := range h.ec.Fields {
@@ -274,6 +511,11 @@ func (h *crudHandler) editForm(c *gin.Context) {
 		if !ok || raw == nil {
 			continue
 		}
+		// Le pilote MySQL renvoie les valeurs textuelles (et les dates sans parseTime) en []byte
+		if b, ok := raw.([]byte); ok {
+			raw = string(b)
+			dataRow[f.Name] = raw
+		}
 
 		switch f.Type {
 		case "datetime":
@@ -281,6 +523,8 @@ func (h *crudHandler) editForm(c *gin.Context) {
 				if t, err := time.Parse(dbFormat, dateStr); err == nil {
 					dataRow[f.Name] = t.Format(f.DisplayFormat)
 				}
+			} else if t, ok := raw.(time.Time); ok { // Le driver SQLite renvoie un time.Time pour les colonnes DATETIME
+				dataRow[f.Name] = t.Format(f.DisplayFormat)
 			}
 		case "number":
 			if ficheDef, ok := h.ec.FicheFieldsByName[f.Name]; ok && ficheDef.DecimalSeparator != "" {
@@ -288,6 +532,12 @@ func (h *crudHandler) editForm(c *gin.Context) {
 					dataRow[f.Name] = formatNumber(num, ficheDef.Decimals, ficheDef.DecimalSeparator, ficheDef.ThousandsSeparator)
 				}
 			}
+		case "money": // Montant sans symbole (affiché à côté du champ), relu par parseMoney
+			if minor, ok := moneyValue(raw, f.Money); ok {
+				dataRow[f.Name] = moneyAmount(minor, f.Money)
+			}
+		case "enum": // Valeur comparée aux options de la liste de choix
+			dataRow[f.Name] = entity.EnumKey(raw)
 		case "boolean": // Nouvelle logique pour les booléens
 			// GORM peut renvoyer des booléens comme int64 (0 ou 1) ou bool
 			if val, ok := raw.(int64); ok {
@@ -305,22 +555,8 @@ func (h *crudHandler) editForm(c *gin.Context) {
 			}
 		}
 	}
-
-	c.HTML(http.StatusOK, "form.html", gin.H{
-		"Entity":    h.ec,
-		"Code":      h.ec.Code,
-		"Mode":      "edit",
-		"DataRow":   dataRow,
-		"Errors":    map[string]string{},
-		"ComboData": h.prepareComboData(),
-		"Page":      c.Query("page"),
-		"PageSize":  c.Query("pageSize"),
-		"SortField": c.Query("sort"),
-		"SortOrder": c.Query("order"),
-	})
 }
 
-
 // update traite la soumission du formulaire de modification.
 func (h *crudHandler) update(c *gin.Context) {
 	id := c.Param("id")
@@ -330,23 +566,132 @@ func (h *crudHandler) update(c *gin.Context) {
 		return
 	}
 
-	updates := h.bindAndConvertForm(c)
+	// La clé primaire n'est pas modifiable : elle désigne l'enregistrement dans l'URL
+	updates, err := h.bindAndConvertForm(c)
+	if err != nil {
+		h.saveFailed(c, "edit", "Erreur de mise à jour", err)
+		return
+	}
+	for _, col := range h.ec.PrimaryKey {
+		delete(updates, col)
+	}
+	subForms := h.postedSubForms(c)
+	user := currentUser(c)
 
-	if err := h.db.Table(h.ec.Table).Where("id = ?", id).Updates(updates).Error; err != nil {
-		c.String(http.StatusBadRequest, "Erreur de mise à jour : %v", err)
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := h.updateRecord(tx, user, id, updates); err != nil {
+			return err
+		}
+		if err := h.saveSubForms(tx, user, id, subForms); err != nil {
+			return err
+		}
+		return releaseLock(tx, h.ec, id, user)
+	})
+	if err != nil {
+		h.saveFailed(c, "edit", "Erreur de mise à jour", err)
 		return
 	}
+	purgeAttachments(h.db)
 
-	redirectURL := fmt.Sprintf(
-		"/%s?page=%s&pageSize=%s&sort=%s&order=%s&highlight=%s",
-		h.ec.List.Name, c.Query("page"), c.Query("pageSize"), c.Query("sort"), c.Query("order"), id,
-	)
-	c.Redirect(http.StatusSeeOther, redirectURL)
+	// La modification peut déplacer l'enregistrement dans le tri : sa page est recalculée
+	c.Redirect(http.StatusSeeOther, h.highlightURL(c, id))
+}
+
+// insertRecord crée un enregistrement dans la transaction tx : hooks, déclencheurs,
+// contraintes et journal sont appliqués ; renvoie la clé encodée de l'enregistrement créé.
+func (h *crudHandler) insertRecord(tx *gorm.DB, user string, vals map[string]interface{}) (string, error) {
+	if err := generateKey(tx, h.ec, vals); err != nil {
+		return "", err
+	}
+	hc := &HookContext{Entity: h.ec, Values: vals, User: user, Tx: tx}
+	if err := runHooks(beforeCreate, hc); err != nil {
+		return "", err
+	}
+	if errs, err := h.ec.Triggers.Apply(vals, nil); err != nil {
+		return "", err
+	} else if len(errs) > 0 {
+		return "", FieldErrors(errs)
+	}
+	if errs, err := h.checkConstraints(tx, vals, ""); err != nil {
+		return "", err
+	} else if len(errs) > 0 {
+		return "", FieldErrors(errs)
+	}
+	parts, err := insertRow(tx, h.ec.Table, h.ec.PrimaryKey, vals)
+	if err != nil {
+		return "", err
+	}
+	hc.ID = encodeKey(parts)
+	if err := attachRecord(tx, h.ec, hc.ID, vals, nil); err != nil {
+		return "", err
+	}
+	if err := runHooks(afterCreate, hc); err != nil {
+		return "", err
+	}
+	if err := writeVersion(tx, h.ec, hc.ID, "create", user, nil); err != nil {
+		return "", err
+	}
+	return hc.ID, writeAudit(tx, h.ec, hc.ID, "create", user, auditValues(vals))
 }
 
-// delete gère la suppression d'un enregistrement.
+// updateRecord met à jour un enregistrement dans la transaction tx : hooks, déclencheurs,
+// contraintes et journal sont appliqués ; une FieldErrors est renvoyée pour les erreurs de saisie.
+func (h *crudHandler) updateRecord(tx *gorm.DB, user, id string, updates map[string]interface{}) error {
+	row, err := whereKey(tx.Table(h.ec.Table), h.ec, id)
+	if err != nil {
+		return err
+	}
+	if err := checkLock(tx, h.ec, id, user); err != nil {
+		return err
+	}
+	previous := make(map[string]interface{})
+	if err := row.Session(&gorm.Session{}).Select("*").Take(&previous).Error; err != nil {
+		return err
+	}
+	hc := &HookContext{Entity: h.ec, ID: id, Values: updates, Previous: previous, User: user, Tx: tx}
+	if err := runHooks(beforeUpdate, hc); err != nil {
+		return err
+	}
+	if errs, err := h.ec.Triggers.Apply(updates, previous); err != nil {
+		return err
+	} else if len(errs) > 0 {
+		return FieldErrors(errs)
+	}
+	if errs, err := h.checkConstraints(tx, updates, id); err != nil {
+		return err
+	} else if len(errs) > 0 {
+		return FieldErrors(errs)
+	}
+	if err := row.Updates(updates).Error; err != nil {
+		return err
+	}
+	if err := attachRecord(tx, h.ec, id, updates, previous); err != nil {
+		return err
+	}
+	if err := runHooks(afterUpdate, hc); err != nil {
+		return err
+	}
+	if changes := auditChanges(previous, updates); len(changes) > 0 {
+		if err := writeVersion(tx, h.ec, id, "update", user, previous); err != nil {
+			return err
+		}
+		return writeAudit(tx, h.ec, id, "update", user, changes)
+	}
+	return nil
+}
+
+// delete gère la suppression d'un enregistrement en respectant les références
+// déclarées par les autres entités (refus, cascade ou mise à NULL).
 func (h *crudHandler) delete(c *gin.Context) {
-	h.db.Table(h.ec.Table).Where("id = ?", c.Param("id")).Delete(nil)
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		return deleteWithReferences(tx, h.ec, c.Param("id"), currentUser(c), map[string]bool{})
+	})
+	if err != nil {
+		log.Printf("[DELETE] %s/%s : %v", h.ec.Name, c.Param("id"), err)
+		c.Redirect(http.StatusSeeOther, "/"+h.ec.List.Name+"?error="+url.QueryEscape(err.Error()))
+		return
+	}
+	purgeAttachments(h.db)
 	c.Redirect(http.StatusSeeOther, "/"+h.ec.List.Name)
 }
 
@@ -355,33 +700,56 @@ func (h *crudHandler) delete(c *gin.Context) {
 // validate exécute les règles de validation définies dans le _code.yaml.
 func (h *crudHandler) validate(c *gin.Context) map[string]string {
 	errors := make(map[string]string)
-	if h.ec.Code == nil {
-		return errors
+	if h.ec.Code != nil {
+		for field, rule := range h.ec.Code.BackValidations {
+			if msg := validateValue(rule, c.PostForm(field)); msg != "" {
+				errors[field] = msg
+			}
+		}
 	}
 
-	for field, rule := range h.ec.Code.BackValidations {
-		raw := c.PostForm(field)
-		if rule.Required && raw == "" {
-			errors[field] = rule.RequiredMessage
-			continue // Si c'est requis et vide, pas la peine de vérifier le reste
+	// Valeurs des champs enum et money, et fichiers déposés (taille et type limités par le champ)
+	for _, f := range h.ec.Fields {
+		msg := ""
+		switch {
+		case f.Enum != nil:
+			msg = checkEnumValue(f, c.PostForm(f.Name))
+		case f.Money != nil:
+			msg = checkMoneyValue(f, c.PostForm(f.Name))
+		case f.Attachment != nil:
+			msg = checkUpload(c, f)
 		}
-		if raw != "" { // On ne valide min/max que si le champ n'est pas vide
-			if rule.Min > 0 && len(raw) < rule.Min {
-				errors[field] = rule.MinMessage
-			}
-			if rule.Max > 0 && len(raw) > rule.Max {
-				errors[field] = rule.MaxMessage
-			}
+		if msg != "" {
+			errors[f.Name] = msg
 		}
 	}
 	return errors
 }
 
+// validateValue applique une règle de validation back à une valeur brute et renvoie
+// le message d'erreur, ou "" si la valeur est valide.
+func validateValue(rule form_codes.BackValidation, raw string) string {
+	if rule.Required && raw == "" {
+		return rule.RequiredMessage // Si c'est requis et vide, pas la peine de vérifier le reste
+	}
+	msg := ""
+	if raw != "" { // On ne valide min/max que si le champ n'est pas vide
+		if rule.Min > 0 && len(raw) < rule.Min {
+			msg = rule.MinMessage
+		}
+		if rule.Max > 0 && len(raw) > rule.Max {
+			msg = rule.MaxMessage
+		}
+	}
+	return msg
+}
+
 // bindAndConvertForm lit les données du formulaire POST, les convertit aux bons types
-// et gère les valeurs vides pour les transformer en nil (corrige le bug).
-func (h *crudHandler) bindAndConvertForm(c *gin.Context) map[string]interface{} {
+// et gère les valeurs vides pour les transformer en nil (corrige le bug). Un fichier déposé
+// qui n'a pas pu être enregistré est renvoyé en erreur : la fiche n'est pas enregistrée sans lui.
+func (h *crudHandler) bindAndConvertForm(c *gin.Context) (map[string]interface{}, error) {
 	values := make(map[string]interface{})
-	
+
 	// Créer une map pour un accès rapide aux propriétés des champs
 	fieldProps := make(map[string]entity.Field)
 	for _, f := range h.ec.Fields {
@@ -391,69 +759,97 @@ func (h *crudHandler) bindAndConvertForm(c *gin.Context) map[string]interface{}
 	for _, grp := range h.ec.Fiche.Groups {
 		for _, fd := range grp.Fields {
 			props, ok := fieldProps[fd.Name]
-			// Ignorer les champs qui ne sont pas dans la config globale, l'ID, ou les champs readonly
-			if !ok || props.Name == "id" || props.ReadOnly {
+			// Ignorer les champs qui ne sont pas dans la config globale, la clé générée, ou les champs readonly
+			if !ok || h.ec.IsGeneratedKey(props.Name) || props.ReadOnly {
 				continue
 			}
 
-			raw := c.PostForm(fd.Name)
-			var finalValue interface{}
+			// Pièce jointe : nouveau fichier déposé, retrait, ou pièce jointe d'une version
+			// restaurée (<champ>__restore), sinon la valeur actuelle est conservée
+			if props.Attachment != nil {
+				if c.PostForm(fd.Name+"__remove") != "" {
+					values[fd.Name] = nil
+				} else if id, err := h.storeUpload(c, props); err != nil {
+					log.Printf("[UPLOAD] %s.%s : %v", h.ec.Name, fd.Name, err)
+					return nil, fmt.Errorf("fichier « %s » non enregistré : %w", fieldLabel(h.ec, fd.Name), err)
+				} else if id != "" {
+					values[fd.Name] = id
+				} else if restored, ok := c.GetPostForm(fd.Name + "__restore"); ok {
+					values[fd.Name] = nil
+					if restored != "" {
+						values[fd.Name] = restored
+					}
+				}
+				continue
+			}
 
-			if raw == "" {
-				if props.Type == "boolean" {
-					finalValue = false
-				} else {
-					finalValue = nil
+			values[fd.Name] = convertFormValue(props, fd, c.PostForm(fd.Name))
+		}
+	}
+	return values, nil
+}
+
+// convertFormValue convertit la valeur brute d'un champ de formulaire vers le type de la colonne.
+// Une valeur vide devient nil (false pour les booléens).
+func convertFormValue(props entity.Field, fd entity.FieldDef, raw string) interface{} {
+	var finalValue interface{}
+
+	if raw == "" {
+		if props.Type == "boolean" {
+			finalValue = false
+		} else {
+			finalValue = nil
+		}
+	} else {
+		isSpecialType := fd.ComboConfig != nil || fd.VisionConfig != nil
+		if isSpecialType {
+			finalValue = raw
+		} else {
+			switch props.Type {
+			case "uint", "int":
+				if i, err := strconv.Atoi(raw); err == nil {
+					finalValue = i
 				}
-			} else {
-				isSpecialType := fd.ComboConfig != nil || fd.VisionConfig != nil
-				if isSpecialType {
-					finalValue = raw
+			case "money":
+				if minor, err := parseMoney(raw, props.Money.Digits); err == nil {
+					finalValue = storeMoney(minor, props.Money)
+				}
+			case "number":
+				cleanRaw := strings.Replace(raw, ",", ".", -1)
+				if f, err := strconv.ParseFloat(cleanRaw, 64); err == nil {
+					finalValue = f
+				}
+			case "boolean":
+				if raw == "on" || raw == "true" || raw == "1" {
+					finalValue = true
 				} else {
-					switch props.Type {
-					case "uint", "int":
-						if i, err := strconv.Atoi(raw); err == nil {
-							finalValue = i
-						}
-					case "number":
-						cleanRaw := strings.Replace(raw, ",", ".", -1)
-						if f, err := strconv.ParseFloat(cleanRaw, 64); err == nil {
-							finalValue = f
-						}
-					case "boolean":
-						if raw == "on" || raw == "true" || raw == "1" {
-							finalValue = true
-						} else {
-							finalValue = false
-						}
-					case "date":
-						if raw == "" { // Si le champ date est vide
-							finalValue = time.Now() // Remplir avec la date/heure actuelle
-						} else if t, err := time.Parse("2006-01-02", raw); err == nil {
-							finalValue = t
-						}
-					case "datetime":
-						if raw == "" { // Si le champ datetime est vide
-							finalValue = time.Now().Format("2006-01-02 15:04:05") // Remplir avec la date/heure actuelle formatée
-						} else if props.DisplayFormat != "" {
-							t, err := time.Parse(props.DisplayFormat, raw)
-							if err == nil {
-								finalValue = t.Format("2006-01-02 15:04:05")
-							} else {
-								finalValue = nil
-							}
-						} else {
-							finalValue = raw
-						}
-					default:
-						finalValue = raw
+					finalValue = false
+				}
+			case "date":
+				if raw == "" { // Si le champ date est vide
+					finalValue = time.Now() // Remplir avec la date/heure actuelle
+				} else if t, err := time.Parse("2006-01-02", raw); err == nil {
+					finalValue = t
+				}
+			case "datetime":
+				if raw == "" { // Si le champ datetime est vide
+					finalValue = time.Now().Format("2006-01-02 15:04:05") // Remplir avec la date/heure actuelle formatée
+				} else if props.DisplayFormat != "" {
+					t, err := time.Parse(props.DisplayFormat, raw)
+					if err == nil {
+						finalValue = t.Format("2006-01-02 15:04:05")
+					} else {
+						finalValue = nil
 					}
+				} else {
+					finalValue = raw
 				}
+			default:
+				finalValue = raw
 			}
-			values[fd.Name] = finalValue
 		}
 	}
-	return values
+	return finalValue
 }
 
 // prepareComboData exécute les requêtes SQL pour tous les combo_base du formulaire.
@@ -463,7 +859,7 @@ func (h *crudHandler) prepareComboData() map[string][]map[string]interface{} {
 		for _, fd := range grp.Fields {
 			if fd.Type == "combo_base" && fd.ComboConfig != nil {
 				var rows []map[string]interface{}
-				h.db.Raw(fd.ComboConfig.SQL).Scan(&rows)
+				h.source(fd.ComboConfig.DataSource).Raw(fd.ComboConfig.SQL).Scan(&rows)
 				opts := make([]map[string]interface{}, 0, len(rows))
 				for _, row := range rows {
 					var parts []string
@@ -483,6 +879,20 @@ func (h *crudHandler) prepareComboData() map[string][]map[string]interface{} {
 	return comboData
 }
 
+// saveFailed ré-affiche la fiche après l'échec d'un enregistrement (transaction annulée) :
+// erreurs par champ, ou message général en tête de fiche.
+func (h *crudHandler) saveFailed(c *gin.Context, mode, prefix string, err error) {
+	if fe, ok := asFieldErrors(err); ok {
+		h.repopulateFormOnError(c, mode, fe)
+		return
+	}
+	log.Printf("[SAVE] %s %s : %v", h.ec.Name, mode, err)
+	h.repopulateFormOnError(c, mode, map[string]string{formErrorKey: prefix + " : " + err.Error()})
+}
+
+// formErrorKey est la clé des erreurs qui ne portent pas sur un champ de la fiche.
+const formErrorKey = "_form"
+
 // repopulateFormOnError ré-affiche le formulaire en cas d'erreur de validation.
 func (h *crudHandler) repopulateFormOnError(c *gin.Context, mode string, errors map[string]string) {
 	dataRow := make(map[string]interface{})
@@ -491,22 +901,57 @@ func (h *crudHandler) repopulateFormOnError(c *gin.Context, mode string, errors
 			dataRow[fd.Name] = c.PostForm(fd.Name)
 		}
 	}
-	// Si on est en mode édition, il faut conserver l'ID.
+	// Si on est en mode édition, il faut conserver la clé (champs non modifiables inclus).
 	if mode == "edit" {
-		dataRow["id"] = c.Param("id")
+		dataRow["_key"] = c.Param("id")
+		if parts, err := decodeKey(c.Param("id"), len(h.ec.PrimaryKey)); err == nil {
+			for i, col := range h.ec.PrimaryKey {
+				dataRow[col] = parts[i]
+			}
+		}
+	}
+
+	// Les fichiers ne sont pas redéposés : la fiche affiche les pièces jointes enregistrées
+	if mode == "edit" && h.ec.HasAttachments() {
+		if q, err := whereKey(h.db.Table(h.ec.Table), h.ec, c.Param("id")); err == nil {
+			stored := make(map[string]interface{})
+			if q.Select("*").Take(&stored).Error == nil {
+				for _, f := range h.ec.Fields {
+					if f.Attachment != nil {
+						dataRow[f.Name] = stored[f.Name]
+					}
+				}
+			}
+		}
+		h.loadAttachments(dataRow)
+	}
+
+	// La fiche reste ouverte : le verrou de l'utilisateur est prolongé
+	var lock gin.H
+	if mode == "edit" {
+		var err error
+		if lock, err = h.lockView(c, c.Param("id")); err != nil {
+			log.Printf("[LOCK] %s/%s : %v", h.ec.Name, c.Param("id"), err)
+		}
 	}
 
 	c.HTML(http.StatusBadRequest, "form.html", gin.H{
-		"Entity":    h.ec,
-		"Code":      h.ec.Code,
-		"Mode":      mode,
-		"DataRow":   dataRow,
-		"Errors":    errors,
-		"ComboData": h.prepareComboData(),
-		"Page":      c.Query("page"),
-		"PageSize":  c.Query("pageSize"),
-		"SortField": c.Query("sort"),
-		"SortOrder": c.Query("order"),
+		"Entity":      h.ec,
+		"Code":        h.ec.Code,
+		"Mode":        mode,
+		"DataRow":     dataRow,
+		"Lock":        lock,
+		"Errors":      errors,
+		"ComboData":   h.prepareComboData(),
+		"SubForms":    h.postedSubForms(c),
+		"History":     h.recentHistory(c.Param("id")),
+		"ListQuery":   template.URL(historyListQuery(c)),
+		"Page":        c.Query("page"),
+		"PageSize":    c.Query("pageSize"),
+		"SortField":   c.Query("sort"),
+		"SortOrder":   c.Query("order"),
+		"Search":      c.Query("search"),
+		"FilterQuery": template.URL(listExtraQuery(c, h.parseFilters(c))),
 	})
 }
 
@@ -521,7 +966,9 @@ func (h *crudHandler) visionData(c *gin.Context) {
 				break
 			}
 		}
-		if vc != nil { break }
+		if vc != nil {
+			break
+		}
 	}
 
 	if vc == nil {
@@ -530,7 +977,7 @@ func (h *crudHandler) visionData(c *gin.Context) {
 	}
 
 	var rows []map[string]interface{}
-	h.db.Raw(vc.SQL).Scan(&rows)
+	h.source(vc.DataSource).Raw(vc.SQL).Scan(&rows)
 	c.JSON(http.StatusOK, rows)
 }
 
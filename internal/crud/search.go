@@ -0,0 +1,144 @@
+// internal/crud/search.go
+package crud
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchHandlers contient les handlers de toutes les entités, pour la recherche globale.
+var searchHandlers []*crudHandler
+
+// globalSearchLimit est le nombre maximal de résultats affichés par entité.
+const globalSearchLimit = 10
+
+// searchHit est un enregistrement trouvé par la recherche globale.
+type searchHit struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// searchGroup regroupe les résultats d'une entité.
+type searchGroup struct {
+	Entity  string      `json:"entity"`
+	Label   string      `json:"label"`
+	Total   int64       `json:"total"`
+	ListURL string      `json:"listUrl"` // Liste de l'entité filtrée sur la recherche
+	Hits    []searchHit `json:"hits"`
+}
+
+// RegisterSearch ajoute la route de recherche globale /search, à appeler après
+// l'enregistrement de toutes les entités.
+func RegisterSearch(r *gin.Engine) {
+	r.GET("/search", globalSearch)
+}
+
+// globalSearch cherche le texte saisi dans les list.searchableFields (ou l'index plein texte)
+// de chaque entité et affiche les résultats groupés par entité. Répond en JSON si le client
+// le demande (Accept: application/json).
+func globalSearch(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	var groups []searchGroup
+	if q != "" {
+		for _, h := range searchHandlers {
+			if g, ok := h.searchEntity(q); ok {
+				groups = append(groups, g)
+			}
+		}
+	}
+
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(http.StatusOK, gin.H{"query": q, "results": groups})
+		return
+	}
+	c.HTML(http.StatusOK, "search.html", gin.H{
+		"Query":  q,
+		"Groups": groups,
+	})
+}
+
+// searchEntity renvoie les premiers résultats de l'entité et leur nombre total.
+func (h *crudHandler) searchEntity(q string) (searchGroup, bool) {
+	where, args, ok := h.searchCondition(q)
+	if !ok {
+		return searchGroup{}, false
+	}
+	g := searchGroup{
+		Entity:  h.ec.Name,
+		Label:   h.ec.LabelPlural,
+		ListURL: "/" + h.ec.List.Name + "?search=" + url.QueryEscape(q),
+	}
+	if err := h.db.Table(h.ec.Table).Where(where, args...).Count(&g.Total).Error; err != nil {
+		log.Printf("[SEARCH] Erreur SQL pour %s : %v", h.ec.Name, err)
+		return searchGroup{}, false
+	}
+	if g.Total == 0 {
+		return searchGroup{}, false
+	}
+
+	query := h.db.Table(h.ec.Table).Where(where, args...)
+	if rank, ok := h.searchRank(q); ok {
+		query = query.Order(rank)
+	}
+	var rows []map[string]interface{}
+	query.Order(keyOrder(h.ec, "ASC")).Limit(globalSearchLimit).Find(&rows)
+
+	titleFields := h.hitTitleFields()
+	for _, row := range rows {
+		id := rowKey(h.ec, row)
+		var parts []string
+		for _, f := range titleFields {
+			v := row[f]
+			if v == nil || fmt.Sprint(v) == "" {
+				continue
+			}
+			if m := h.ec.FieldsByName[f].Money; m != nil {
+				v = displayMoney(v, m)
+			} else if h.ec.FieldsByName[f].Type == "number" {
+				if num, err := strconv.ParseFloat(fmt.Sprint(v), 64); err == nil {
+					v = h.formatListNumber(f, num)
+				}
+			}
+			parts = append(parts, fmt.Sprint(v))
+		}
+		title := strings.Join(parts, " — ")
+		if title == "" {
+			for _, col := range h.ec.PrimaryKey {
+				parts = append(parts, keyPart(row[col]))
+			}
+			title = h.ec.Label + " " + strings.Join(parts, " / ")
+		}
+		g.Hits = append(g.Hits, searchHit{
+			ID:    id,
+			Title: title,
+			URL:   "/" + h.ec.Fiche.Name + "/edit/" + id,
+		})
+	}
+	return g, true
+}
+
+// hitTitleFields renvoie les champs composant le libellé d'un résultat :
+// champs de recherche hors clé primaire, sinon colonnes de la liste hors clé et booléens.
+func (h *crudHandler) hitTitleFields() []string {
+	var fields []string
+	for _, f := range h.ec.List.SearchableFields {
+		if !h.ec.IsPrimaryKey(f) {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		for _, f := range h.ec.List.Columns {
+			if !h.ec.IsPrimaryKey(f) && h.ec.FieldsByName[f].Type != "boolean" {
+				fields = append(fields, f)
+			}
+		}
+	}
+	return fields
+}
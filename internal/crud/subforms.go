@@ -0,0 +1,258 @@
+// internal/crud/subforms.go
+package crud
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/go-crud/internal/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// subFormView est une sous-fiche affichée dans la fiche du parent : les lignes d'une entité
+// enfant, saisies dans la même page et enregistrées dans la même transaction que le parent.
+type subFormView struct {
+	Config  entity.SubFormConfig
+	Child   *entity.EntityConfig
+	Columns []entity.Field
+	Rows    []subFormRow
+	Next    int // Premier index libre pour les lignes ajoutées dans la page
+}
+
+// subFormRow est une ligne enfant, lue en base ou ressaisie après une erreur.
+type subFormRow struct {
+	Index  int
+	ID     string                 // Clé encodée, vide pour une nouvelle ligne
+	Values map[string]interface{} // Valeurs affichées (texte saisi ou valeur formatée)
+	Delete bool                   // Ligne existante cochée pour suppression
+}
+
+// subFormField renvoie le nom du champ de formulaire d'une cellule de sous-fiche.
+func subFormField(name string, index int, field string) string {
+	return fmt.Sprintf("sub.%s.%d.%s", name, index, field)
+}
+
+// childHandler renvoie le handler de l'entité enfant d'une sous-fiche. L'enfant doit être
+// dans la même source de données que le parent (même transaction).
+func (h *crudHandler) childHandler(sf entity.SubFormConfig) (*crudHandler, error) {
+	for _, other := range searchHandlers {
+		if other.ec.Name != sf.Entity {
+			continue
+		}
+		if other.db != h.db {
+			return nil, fmt.Errorf("sous-fiche %s : l'entité %s est dans une autre source de données", sf.Name, sf.Entity)
+		}
+		if _, ok := other.ec.FieldsByName[sf.ForeignKey]; !ok {
+			return nil, fmt.Errorf("sous-fiche %s : champ %s inconnu dans %s", sf.Name, sf.ForeignKey, sf.Entity)
+		}
+		return other, nil
+	}
+	return nil, fmt.Errorf("sous-fiche %s : entité %s non enregistrée", sf.Name, sf.Entity)
+}
+
+// subFormColumns renvoie les champs saisis dans la sous-fiche (hors clé générée, clé étrangère et lecture seule).
+func subFormColumns(sf entity.SubFormConfig, child *entity.EntityConfig) []entity.Field {
+	var cols []entity.Field
+	for _, name := range sf.Columns {
+		f, ok := child.FieldsByName[name]
+		if !ok || child.IsGeneratedKey(name) || name == sf.ForeignKey || f.ReadOnly {
+			continue
+		}
+		cols = append(cols, f)
+	}
+	return cols
+}
+
+// parentValue renvoie la valeur de la clé étrangère des lignes enfants : la clé (simple)
+// du parent, décodée.
+func parentValue(parentID string) string {
+	if parts, err := decodeKey(parentID, 1); err == nil {
+		return parts[0]
+	}
+	return parentID
+}
+
+// subForms charge les lignes enfants de l'enregistrement parentID (aucune en création).
+func (h *crudHandler) subForms(parentID string) []subFormView {
+	var views []subFormView
+	for _, sf := range h.ec.Fiche.SubForms {
+		child, err := h.childHandler(sf)
+		if err != nil {
+			log.Printf("[SUBFORM] %v", err)
+			continue
+		}
+		v := subFormView{Config: sf, Child: child.ec, Columns: subFormColumns(sf, child.ec)}
+		if parentID != "" {
+			var rows []map[string]interface{}
+			h.db.Table(child.ec.Table).Where(sf.ForeignKey+" = ?", parentValue(parentID)).Order(keyOrder(child.ec, "ASC")).Find(&rows)
+			for i, row := range rows {
+				child.formatForForm(row)
+				for _, f := range v.Columns {
+					if t, ok := row[f.Name].(time.Time); ok && f.Type == "date" {
+						row[f.Name] = t.Format("2006-01-02")
+					}
+				}
+				v.Rows = append(v.Rows, subFormRow{Index: i, ID: rowKey(child.ec, row), Values: row})
+			}
+		}
+		v.Next = len(v.Rows)
+		views = append(views, v)
+	}
+	return views
+}
+
+// postedSubForms relit les lignes des sous-fiches soumises avec la fiche. Les champs sont
+// nommés sub.<sous-fiche>.<index>.<champ> ; chaque ligne porte un champ id (vide si nouvelle).
+func (h *crudHandler) postedSubForms(c *gin.Context) []subFormView {
+	if len(h.ec.Fiche.SubForms) == 0 {
+		return nil
+	}
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		log.Printf("[SUBFORM] Lecture du formulaire : %v", err)
+	}
+	var views []subFormView
+	for _, sf := range h.ec.Fiche.SubForms {
+		child, err := h.childHandler(sf)
+		if err != nil {
+			log.Printf("[SUBFORM] %v", err)
+			continue
+		}
+		v := subFormView{Config: sf, Child: child.ec, Columns: subFormColumns(sf, child.ec)}
+		rows := make(map[int]*subFormRow)
+		prefix := "sub." + sf.Name + "."
+		for key, vals := range c.Request.PostForm {
+			rest, ok := strings.CutPrefix(key, prefix)
+			if !ok || len(vals) == 0 {
+				continue
+			}
+			idx, field, ok := strings.Cut(rest, ".")
+			i, err := strconv.Atoi(idx)
+			if !ok || err != nil || i < 0 {
+				continue
+			}
+			row, ok := rows[i]
+			if !ok {
+				row = &subFormRow{Index: i, Values: make(map[string]interface{})}
+				rows[i] = row
+			}
+			switch field {
+			case "id":
+				row.ID = vals[0]
+			case "_delete":
+				row.Delete = vals[0] != ""
+			default:
+				row.Values[field] = vals[0]
+			}
+		}
+		for _, row := range rows {
+			v.Rows = append(v.Rows, *row)
+			if row.Index >= v.Next {
+				v.Next = row.Index + 1
+			}
+		}
+		sort.Slice(v.Rows, func(i, j int) bool { return v.Rows[i].Index < v.Rows[j].Index })
+		views = append(views, v)
+	}
+	return views
+}
+
+// saveSubForms enregistre les lignes des sous-fiches dans la transaction du parent : suppression
+// des lignes cochées, modification des lignes existantes et création des nouvelles lignes
+// (les lignes nouvelles laissées vides sont ignorées). Hooks, déclencheurs, contraintes et
+// journal de l'entité enfant s'appliquent ; les erreurs de saisie sont renvoyées par cellule.
+func (h *crudHandler) saveSubForms(tx *gorm.DB, user, parentID string, views []subFormView) error {
+	errs := make(FieldErrors)
+	parent := parentValue(parentID)
+	for _, v := range views {
+		child, err := h.childHandler(v.Config)
+		if err != nil {
+			return err
+		}
+		fk := v.Config.ForeignKey
+		for _, row := range v.Rows {
+			if row.ID == "" && (row.Delete || emptySubFormRow(v.Columns, row)) {
+				continue
+			}
+			if row.ID != "" {
+				var count int64
+				q, err := whereKey(tx.Table(child.ec.Table), child.ec, row.ID)
+				if err != nil {
+					return err
+				}
+				if err := q.Where(fk+" = ?", parent).Count(&count).Error; err != nil {
+					return err
+				}
+				if count == 0 {
+					return fmt.Errorf("%s : la ligne %s n'appartient pas à cet enregistrement", v.Config.Label, row.ID)
+				}
+			}
+			if row.Delete {
+				if err := deleteWithReferences(tx, child.ec, row.ID, user, map[string]bool{}); err != nil {
+					return fmt.Errorf("%s : %w", v.Config.Label, err)
+				}
+				continue
+			}
+
+			// Validation back de l'enfant (règles, valeurs enum et money) puis conversion, comme
+			// pour sa propre fiche
+			vals := make(map[string]interface{})
+			rowErrs := make(map[string]string)
+			for _, f := range v.Columns {
+				raw, _ := row.Values[f.Name].(string)
+				if child.ec.Code != nil {
+					if rule, ok := child.ec.Code.BackValidations[f.Name]; ok {
+						if msg := validateValue(rule, raw); msg != "" {
+							rowErrs[f.Name] = msg
+							continue
+						}
+					}
+				}
+				msg := checkEnumValue(f, raw)
+				if msg == "" {
+					msg = checkMoneyValue(f, raw)
+				}
+				if msg != "" {
+					rowErrs[f.Name] = msg
+					continue
+				}
+				vals[f.Name] = convertFormValue(f, child.ec.FicheFieldsByName[f.Name], raw)
+			}
+			if len(rowErrs) == 0 {
+				vals[fk] = parent
+				if row.ID == "" {
+					_, err = child.insertRecord(tx, user, vals)
+				} else {
+					err = child.updateRecord(tx, user, row.ID, vals)
+				}
+				if fe, ok := asFieldErrors(err); ok {
+					rowErrs = fe
+				} else if err != nil {
+					return fmt.Errorf("%s : %w", v.Config.Label, err)
+				}
+			}
+			for field, msg := range rowErrs {
+				errs[subFormField(v.Config.Name, row.Index, field)] = fmt.Sprintf("%s, ligne %d : %s", v.Config.Label, row.Index+1, msg)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// emptySubFormRow indique si aucune cellule d'une nouvelle ligne n'a été saisie.
+func emptySubFormRow(cols []entity.Field, row subFormRow) bool {
+	for _, f := range cols {
+		if s, _ := row.Values[f.Name].(string); s != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+// internal/crud/subforms_test.go
+package crud
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"example.com/go-crud/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+// Une ligne de sous-fiche invalide annule tout l'enregistrement (parent et lignes valides)
+// et la fiche est ré-affichée avec l'erreur sur la cellule.
+func TestSubFormsRollback(t *testing.T) {
+	h := hookHandler(t, "commande")
+	h.db.Exec("INSERT INTO commande (id, nom) VALUES (1, 'dupont')")
+	if err := h.db.Exec("CREATE TABLE ligne (id INTEGER PRIMARY KEY, commande_id INTEGER, statut TEXT)").Error; err != nil {
+		t.Fatal(err)
+	}
+	fields := []entity.Field{
+		{Name: "id", Type: "int"},
+		{Name: "commande_id", Type: "int"},
+		{Name: "statut", Type: "enum", Enum: &entity.EnumConfig{Values: []entity.EnumValue{{Value: "ouvert"}, {Value: "clos"}}}},
+	}
+	child := &crudHandler{db: h.db, ec: &entity.EntityConfig{
+		Name:         "ligne",
+		Table:        "ligne",
+		PrimaryKey:   []string{"id"},
+		Fields:       fields,
+		FieldsByName: map[string]entity.Field{"id": fields[0], "commande_id": fields[1], "statut": fields[2]},
+	}}
+	saved := searchHandlers
+	searchHandlers = append(append([]*crudHandler{}, saved...), h, child)
+	t.Cleanup(func() { searchHandlers = saved })
+	h.ec.Fiche.SubForms = []entity.SubFormConfig{{Name: "lignes", Label: "Lignes", Entity: "ligne", ForeignKey: "commande_id", Columns: []string{"statut"}}}
+
+	// Première ligne valide (écrite avant l'erreur), seconde hors des valeurs de l'enum
+	form := url.Values{
+		"nom":                 {"durand"},
+		"sub.lignes.0.id":     {""},
+		"sub.lignes.0.statut": {"ouvert"},
+		"sub.lignes.1.id":     {""},
+		"sub.lignes.1.statut": {"perdu"},
+	}
+	tests := []struct {
+		name   string
+		handle gin.HandlerFunc
+		id     string
+	}{
+		{"création", h.create, ""},
+		{"modification", h.update, "1"},
+	}
+	for _, tc := range tests {
+		status, fc := postForm(t, tc.handle, "/commandeFiche", tc.id, form)
+		if status != http.StatusBadRequest || fc.name != "form.html" {
+			t.Errorf("%s : statut %d, gabarit %q ; attendu la fiche en 400", tc.name, status, fc.name)
+			continue
+		}
+		want := map[string]string{"sub.lignes.1.statut": "Lignes, ligne 2 : Valeur « perdu » non autorisée"}
+		if !reflect.DeepEqual(fc.data["Errors"], want) {
+			t.Errorf("%s : erreurs %v, attendu %v", tc.name, fc.data["Errors"], want)
+		}
+		// Les lignes saisies sont ré-affichées telles quelles
+		if views, _ := fc.data["SubForms"].([]subFormView); len(views) != 1 || len(views[0].Rows) != 2 || views[0].Rows[1].Values["statut"] != "perdu" {
+			t.Errorf("%s : sous-fiches ré-affichées %+v", tc.name, fc.data["SubForms"])
+		}
+	}
+
+	if got, want := names(t, h), []string{"dupont"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("commandes enregistrées %q, attendu %q", got, want)
+	}
+	var lines, audits int64
+	h.db.Table("ligne").Count(&lines)
+	h.db.Model(&AuditEntry{}).Count(&audits)
+	if lines != 0 || audits != 0 {
+		t.Errorf("%d lignes et %d entrées de journal écrites malgré l'annulation", lines, audits)
+	}
+}
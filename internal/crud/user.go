@@ -0,0 +1,105 @@
+// internal/crud/user.go
+package crud
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminUser est l'identifiant de l'administrateur (config admin.username), seul autorisé
+// à forcer le verrou d'un enregistrement.
+var adminUser string
+
+// SetAdminUser déclare l'identifiant de l'administrateur de l'application.
+func SetAdminUser(name string) {
+	adminUser = name
+}
+
+// isAdmin indique si l'utilisateur courant est l'administrateur.
+func isAdmin(c *gin.Context) bool {
+	return adminUser != "" && currentUser(c) == adminUser
+}
+
+// accounts associe à chaque identifiant son mot de passe (admin et config users).
+var accounts map[string]string
+
+// SetAccounts déclare les comptes acceptés en Basic Auth.
+func SetAccounts(users map[string]string) {
+	accounts = users
+}
+
+// trustedProxies sont les réseaux des proxys dont l'en-tête X-Remote-User est accepté.
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies déclare les proxys de confiance (adresses IP ou réseaux CIDR, config
+// server.trusted_proxies).
+func SetTrustedProxies(proxies []string) error {
+	trustedProxies = nil
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(p)
+		if err != nil {
+			return fmt.Errorf("proxy de confiance invalide « %s »", p)
+		}
+		trustedProxies = append(trustedProxies, network)
+	}
+	return nil
+}
+
+// fromTrustedProxy indique si la requête arrive directement d'un proxy de confiance.
+func fromTrustedProxy(c *gin.Context) bool {
+	ip := net.ParseIP(c.RemoteIP())
+	for _, network := range trustedProxies {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPassword vérifie le mot de passe d'un compte, en temps constant.
+func checkPassword(user, pass string) bool {
+	want, ok := accounts[user]
+	return ok && want != "" && subtle.ConstantTimeCompare([]byte(pass), []byte(want)) == 1
+}
+
+// Identify est le middleware qui établit l'utilisateur courant (valeur "user" du contexte) :
+// login Basic Auth dont le mot de passe est vérifié, sinon en-tête X-Remote-User posé par un
+// proxy de confiance. Des identifiants Basic Auth erronés sont refusés ; sans identifiants,
+// la requête est anonyme. Une valeur "user" posée par un middleware précédent est conservée.
+func Identify() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("user") != "" {
+			c.Next()
+			return
+		}
+		if user, pass, ok := c.Request.BasicAuth(); ok {
+			if !checkPassword(user, pass) {
+				c.Header("WWW-Authenticate", `Basic realm="go-crud"`)
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			c.Set("user", user)
+		} else if user := c.GetHeader("X-Remote-User"); user != "" && fromTrustedProxy(c) {
+			c.Set("user", user)
+		}
+		c.Next()
+	}
+}
+
+// currentUser renvoie l'identifiant de l'utilisateur authentifié par Identify (ou par un
+// middleware d'authentification), "" pour une requête anonyme.
+func currentUser(c *gin.Context) string {
+	return c.GetString("user")
+}
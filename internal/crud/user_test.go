@@ -0,0 +1,111 @@
+// internal/crud/user_test.go
+package crud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSetTrustedProxies(t *testing.T) {
+	tests := []struct {
+		proxies []string
+		wantErr bool
+	}{
+		{nil, false},
+		{[]string{"127.0.0.1", "10.0.0.0/8", "::1", "fd00::/8"}, false},
+		{[]string{"proxy.local"}, true},
+		{[]string{"10.0.0.0/33"}, true},
+		{[]string{"127.0.0.1", ""}, true},
+	}
+	for _, tc := range tests {
+		if err := SetTrustedProxies(tc.proxies); (err != nil) != tc.wantErr {
+			t.Errorf("SetTrustedProxies(%q) = %v", tc.proxies, err)
+		}
+	}
+	SetTrustedProxies(nil)
+}
+
+func TestIdentify(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetAccounts(map[string]string{"alice": "secret", "admin": "root", "vide": ""})
+	if err := SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		SetAccounts(nil)
+		SetTrustedProxies(nil)
+	})
+
+	r := gin.New()
+	r.Use(Identify())
+	r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, currentUser(c)) })
+
+	tests := []struct {
+		name       string
+		remote     string
+		basic      []string // Identifiant et mot de passe Basic Auth
+		remoteUser string   // En-tête X-Remote-User
+		status     int
+		user       string
+	}{
+		{"anonyme", "192.0.2.1:1234", nil, "", http.StatusOK, ""},
+		{"Basic Auth vérifiée", "192.0.2.1:1234", []string{"alice", "secret"}, "", http.StatusOK, "alice"},
+		{"mauvais mot de passe", "192.0.2.1:1234", []string{"admin", "wrong"}, "", http.StatusUnauthorized, ""},
+		{"compte inconnu", "192.0.2.1:1234", []string{"mallory", "x"}, "", http.StatusUnauthorized, ""},
+		{"mot de passe vide refusé", "192.0.2.1:1234", []string{"vide", ""}, "", http.StatusUnauthorized, ""},
+		{"X-Remote-User d'un client", "192.0.2.1:1234", nil, "admin", http.StatusOK, ""},
+		{"X-Remote-User d'un proxy de confiance", "10.1.2.3:1234", nil, "bob", http.StatusOK, "bob"},
+		{"Basic Auth prioritaire sur l'en-tête", "10.1.2.3:1234", []string{"alice", "secret"}, "admin", http.StatusOK, "alice"},
+		{"Basic Auth erronée derrière le proxy", "10.1.2.3:1234", []string{"admin", "wrong"}, "admin", http.StatusUnauthorized, ""},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remote
+		if tc.basic != nil {
+			req.SetBasicAuth(tc.basic[0], tc.basic[1])
+		}
+		if tc.remoteUser != "" {
+			req.Header.Set("X-Remote-User", tc.remoteUser)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Errorf("%s : statut %d, attendu %d", tc.name, w.Code, tc.status)
+			continue
+		}
+		if tc.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s : en-tête WWW-Authenticate absent", tc.name)
+		}
+		if tc.status == http.StatusOK && w.Body.String() != tc.user {
+			t.Errorf("%s : utilisateur %q, attendu %q", tc.name, w.Body.String(), tc.user)
+		}
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() { SetAdminUser("") })
+	tests := []struct {
+		admin string
+		user  string
+		want  bool
+	}{
+		{"admin", "admin", true},
+		{"admin", "alice", false},
+		{"admin", "", false},
+		{"", "", false}, // Sans administrateur configuré, un anonyme n'est pas administrateur
+	}
+	for _, tc := range tests {
+		SetAdminUser(tc.admin)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		if tc.user != "" {
+			c.Set("user", tc.user)
+		}
+		if got := isAdmin(c); got != tc.want {
+			t.Errorf("isAdmin(admin=%q, user=%q) = %v", tc.admin, tc.user, got)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// internal/crud/views.go
+package crud
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/go-crud/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+// SavedView est une vue de liste (filtres, tri, taille de page, colonnes) enregistrée par un utilisateur.
+type SavedView struct {
+	ID        uint   `gorm:"primaryKey"`
+	List      string `gorm:"index;size:100"` // Nom du formulaire liste (ex. compteList)
+	Name      string `gorm:"size:100"`
+	Owner     string `gorm:"index;size:100"`
+	Shared    bool   // Visible par tous les utilisateurs
+	Query     string // Paramètres d'URL de la liste (sort, order, pageSize, search, f_*, cols)
+	CreatedAt time.Time
+}
+
+// listView est une vue proposée dans la liste déroulante de la page liste.
+type listView struct {
+	Key    string // "cfg:<nom>" pour une vue du YAML, identifiant pour une vue enregistrée
+	Title  string
+	URL    template.URL
+	Shared bool
+	Mine   bool // Vue enregistrée par l'utilisateur courant (supprimable)
+	ID     uint
+}
+
+// listStateParams sont les paramètres d'URL qui ne font pas partie de l'état d'une vue.
+var listStateParams = []string{"page", "view", "highlight", "error"}
+
+// currentListQuery renvoie les paramètres de la liste courante à mémoriser dans une vue.
+func currentListQuery(c *gin.Context) string {
+	q := c.Request.URL.Query()
+	for _, k := range listStateParams {
+		q.Del(k)
+	}
+	return q.Encode()
+}
+
+// listExtraQuery encode les paramètres à conserver dans les liens de tri et de pagination :
+// filtres actifs, colonnes choisies et vue courante.
+func listExtraQuery(c *gin.Context, filters []listFilter) string {
+	q, _ := url.ParseQuery(filterQuery(filters))
+	for _, k := range []string{"cols", "view"} {
+		if v := c.Query(k); v != "" {
+			q.Set(k, v)
+		}
+	}
+	// Un regroupement vide est explicite : il remplace le list.groupBy du YAML
+	if v, ok := c.GetQuery("group"); ok {
+		q.Set("group", v)
+	}
+	return q.Encode()
+}
+
+// configViewQuery construit les paramètres d'URL d'une vue prédéfinie du YAML.
+func configViewQuery(v entity.ListViewConfig) string {
+	q := url.Values{}
+	if v.Sort != "" {
+		q.Set("sort", v.Sort)
+	}
+	if v.Order != "" {
+		q.Set("order", v.Order)
+	}
+	if v.PageSize > 0 {
+		q.Set("pageSize", strconv.Itoa(v.PageSize))
+	}
+	if v.Search != "" {
+		q.Set("search", v.Search)
+	}
+	for k, val := range v.Filters {
+		q.Set("f_"+k, val)
+	}
+	if len(v.Columns) > 0 {
+		q.Set("cols", strings.Join(v.Columns, ","))
+	}
+	return q.Encode()
+}
+
+// listViews renvoie les vues prédéfinies puis les vues enregistrées visibles par l'utilisateur
+// (les siennes et celles partagées), ainsi que le titre de la vue active s'il y en a un.
+func (h *crudHandler) listViews(c *gin.Context) ([]listView, string) {
+	active := c.Query("view")
+	title := ""
+	var views []listView
+
+	for _, v := range h.ec.List.Views {
+		key := "cfg:" + v.Name
+		views = append(views, listView{
+			Key:    key,
+			Title:  v.Title,
+			URL:    template.URL("?" + configViewQuery(v) + "&view=" + url.QueryEscape(key)),
+			Shared: true,
+		})
+		if key == active {
+			title = v.Title
+		}
+	}
+
+	user := currentUser(c)
+	var saved []SavedView
+	h.db.Where("list = ? AND (owner = ? OR shared = ?)", h.ec.List.Name, user, true).
+		Order("name").Find(&saved)
+	for _, v := range saved {
+		key := strconv.FormatUint(uint64(v.ID), 10)
+		views = append(views, listView{
+			Key:    key,
+			Title:  v.Name,
+			URL:    template.URL("?" + v.Query + "&view=" + key),
+			Shared: v.Shared,
+			Mine:   v.Owner == user,
+			ID:     v.ID,
+		})
+	}
+	return views, title
+}
+
+// saveView enregistre l'état courant de la liste sous un nom, pour l'utilisateur courant.
+func (h *crudHandler) saveView(c *gin.Context) {
+	name := strings.TrimSpace(c.PostForm("name"))
+	if name == "" {
+		c.Redirect(http.StatusSeeOther, "/"+h.ec.List.Name+"?"+c.PostForm("query")+"&error="+url.QueryEscape("Le nom de la vue est obligatoire."))
+		return
+	}
+	// On ne conserve que des paramètres d'URL bien formés
+	q, err := url.ParseQuery(c.PostForm("query"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Paramètres de vue invalides : %v", err)
+		return
+	}
+	for _, k := range listStateParams {
+		q.Del(k)
+	}
+	view := SavedView{
+		List:   h.ec.List.Name,
+		Name:   name,
+		Owner:  currentUser(c),
+		Shared: c.PostForm("shared") != "",
+		Query:  q.Encode(),
+	}
+	if err := h.db.Create(&view).Error; err != nil {
+		c.String(http.StatusInternalServerError, "Erreur d'enregistrement de la vue : %v", err)
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/"+h.ec.List.Name+"?"+view.Query+"&view="+strconv.FormatUint(uint64(view.ID), 10))
+}
+
+// deleteView supprime une vue enregistrée ; seul son propriétaire peut la supprimer.
+func (h *crudHandler) deleteView(c *gin.Context) {
+	res := h.db.Where("id = ? AND list = ? AND owner = ?", c.Param("id"), h.ec.List.Name, currentUser(c)).Delete(&SavedView{})
+	if res.Error != nil {
+		c.String(http.StatusInternalServerError, "Erreur de suppression de la vue : %v", res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.String(http.StatusForbidden, "Vue introuvable ou appartenant à un autre utilisateur")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/"+h.ec.List.Name)
+}
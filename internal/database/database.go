@@ -0,0 +1,145 @@
+// internal/database/database.go
+package database
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"example.com/go-crud/config"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Open ouvre la base décrite par la configuration. SQLite (défaut) utilise le fichier
+// directory/name, ou le dsn s'il est renseigné ; PostgreSQL et MySQL utilisent le dsn.
+//
+//	postgres : "host=localhost user=crud password=... dbname=crud sslmode=disable"
+//	mysql    : "crud:...@tcp(localhost:3306)/crud?charset=utf8mb4&parseTime=True&loc=Local"
+func Open(cfg config.DatabaseConfig, gormCfg *gorm.Config) (*gorm.DB, error) {
+	dialector, err := Dialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, gormCfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := configurePool(db, cfg); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// configurePool applique les réglages du pool de connexions de la source.
+func configurePool(db *gorm.DB, cfg config.DatabaseConfig) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime != "" {
+		d, err := time.ParseDuration(cfg.ConnMaxLifetime)
+		if err != nil {
+			return fmt.Errorf("conn_max_lifetime invalide : %w", err)
+		}
+		sqlDB.SetConnMaxLifetime(d)
+	}
+	if cfg.ConnMaxIdleTime != "" {
+		d, err := time.ParseDuration(cfg.ConnMaxIdleTime)
+		if err != nil {
+			return fmt.Errorf("conn_max_idle_time invalide : %w", err)
+		}
+		sqlDB.SetConnMaxIdleTime(d)
+	}
+	return nil
+}
+
+// Sources regroupe la base principale et les sources de données nommées (datasources).
+type Sources struct {
+	Default *gorm.DB
+	Named   map[string]*gorm.DB
+}
+
+// DefaultSource est le nom réservé de la base principale (section database).
+const DefaultSource = "default"
+
+// OpenAll ouvre la base principale puis chaque source nommée de la configuration.
+func OpenAll(cfg *config.Config, gormCfg *gorm.Config) (*Sources, error) {
+	s := &Sources{Named: make(map[string]*gorm.DB)}
+	log.Printf("Connecting %s", Describe(cfg.Database))
+	db, err := Open(cfg.Database, gormCfg)
+	if err != nil {
+		return nil, err
+	}
+	s.Default = db
+	for name, dsCfg := range cfg.DataSources {
+		if name == "" || name == DefaultSource {
+			return nil, fmt.Errorf("nom de source de données réservé : %q", name)
+		}
+		log.Printf("Connecting datasource %s : %s", name, Describe(dsCfg))
+		db, err := Open(dsCfg, gormCfg)
+		if err != nil {
+			return nil, fmt.Errorf("source de données %s : %w", name, err)
+		}
+		s.Named[name] = db
+	}
+	return s, nil
+}
+
+// Get renvoie la source nommée ; un nom vide ou "default" désigne la base principale.
+func (s *Sources) Get(name string) (*gorm.DB, error) {
+	if name == "" || name == DefaultSource {
+		return s.Default, nil
+	}
+	db, ok := s.Named[name]
+	if !ok {
+		return nil, fmt.Errorf("source de données inconnue : %s", name)
+	}
+	return db, nil
+}
+
+// Dialector renvoie le pilote GORM correspondant à la configuration.
+func Dialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "", "sqlite":
+		dsn := cfg.DSN
+		if dsn == "" {
+			dsn = filepath.Join(cfg.Directory, cfg.Name)
+		}
+		return sqlite.Open(dsn), nil
+	case "postgres":
+		if cfg.DSN == "" {
+			return nil, fmt.Errorf("dsn obligatoire pour le pilote postgres")
+		}
+		return postgres.Open(cfg.DSN), nil
+	case "mysql":
+		if cfg.DSN == "" {
+			return nil, fmt.Errorf("dsn obligatoire pour le pilote mysql")
+		}
+		return mysql.Open(cfg.DSN), nil
+	default:
+		return nil, fmt.Errorf("pilote de base de données inconnu : %s (sqlite, postgres ou mysql)", cfg.Driver)
+	}
+}
+
+// Describe renvoie une description de la base pour les journaux, sans mot de passe.
+func Describe(cfg config.DatabaseConfig) string {
+	switch cfg.Driver {
+	case "", "sqlite":
+		if cfg.DSN != "" {
+			return "SQLite " + cfg.DSN
+		}
+		return "SQLite " + filepath.Join(cfg.Directory, cfg.Name)
+	default:
+		return cfg.Driver
+	}
+}
@@ -5,9 +5,14 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"example.com/go-crud/config/form_codes"
 	"example.com/go-crud/config/loader"
+	"example.com/go-crud/internal/trigger"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,6 +22,7 @@ type ComboFieldConfig struct {
 	KeyField      string   `yaml:"keyField"`
 	DisplayFields []string `yaml:"displayFields"`
 	Separator     string   `yaml:"separator"`
+	DataSource    string   `yaml:"datasource,omitempty"` // Source de données nommée de la requête (défaut : celle de l'entité)
 }
 
 // VisionFieldConfig est pour le CHAMP de type vision (popup)
@@ -26,6 +32,7 @@ type VisionFieldConfig struct {
 	DisplayFields []string `yaml:"displayFields"`
 	ReturnField   string   `yaml:"returnField"`
 	ModalTitle    string   `yaml:"modalTitle"`
+	DataSource    string   `yaml:"datasource,omitempty"` // Source de données nommée de la requête (défaut : celle de l'entité)
 }
 
 // --- NOUVEAUX types de config pour le FORMULAIRE de type 'vision' ---
@@ -53,6 +60,8 @@ type VisionFormConfig struct {
 	SQL     string              `yaml:"sql"`
 	Params  []VisionParamConfig `yaml:"params"`
 	Actions VisionActionsConfig `yaml:"actions"`
+	// Source de données nommée de la requête (défaut : celle de l'entité)
+	DataSource string `yaml:"datasource,omitempty"`
 
 	// Champs réutilisés de ListConfig pour l'affichage
 	Columns          []string          `yaml:"columns"`
@@ -61,9 +70,18 @@ type VisionFormConfig struct {
 	DefaultSortOrder string            `yaml:"defaultSortOrder"`
 	PageSize         int               `yaml:"pageSize"`
 	PageSizeOptions  []int             `yaml:"pageSizeOptions"`
+	Footer           []AggregateConfig `yaml:"footer,omitempty"` // Agrégats affichés en pied de tableau
 	// Temporary comment to force re-parsing
 }
 
+// AggregateConfig déclare un agrégat de pied de liste, calculé en SQL sur toutes les
+// lignes filtrées (et non sur la seule page affichée).
+type AggregateConfig struct {
+	Field string `yaml:"field"`
+	Func  string `yaml:"func"`            // "sum", "avg", "min", "max" ou "count"
+	Label string `yaml:"label,omitempty"` // Libellé affiché devant la valeur (défaut selon la fonction)
+}
+
 // FieldDef représente un champ dans un formulaire (fiche)
 type FieldDef struct {
 	Name               string             `yaml:"name"`
@@ -105,43 +123,198 @@ type Group struct {
 
 // FicheConfig configuration pour la fiche
 type FicheConfig struct {
-	Name                       string            `yaml:"name"`
-	Groups                     []Group           `yaml:"groups"`
-	Labels                     map[string]string `yaml:"labels"`
-	Width                      string            `yaml:"width,omitempty"`
-	MaxWidth                   string            `yaml:"maxWidth,omitempty"` // Ajout du champ MaxWidth
-	FormBackgroundColor        string            `yaml:"formBackgroundColor,omitempty"`
-	PageBackgroundColor        string            `yaml:"pageBackgroundColor,omitempty"`
-	TabInactiveBackgroundColor string            `yaml:"tabInactiveBackgroundColor,omitempty"` // Nouveau champ
-	TabActiveBackgroundColor   string            `yaml:"tabActiveBackgroundColor,omitempty"`   // Nouveau champ
-	TabContentBackgroundColor  string            `yaml:"tabContentBackgroundColor,omitempty"`  // Nouveau champ
-	LabelColumnWidth           string            `yaml:"labelColumnWidth,omitempty"`           // Nouveau champ
-	TabLabelFontSize           string            `yaml:"tabLabelFontSize,omitempty"`           // Nouveau champ
-	ButtonFontSize             string            `yaml:"buttonFontSize,omitempty"`             // Nouveau champ
-	FormActionButtonsFontSize  string            `yaml:"formActionButtonsFontSize,omitempty"`  // Nouveau champ
-	FormContentMaxHeightAdjustment string        `yaml:"formContentMaxHeightAdjustment,omitempty"` // Nouveau champ
+	Name                           string            `yaml:"name"`
+	Groups                         []Group           `yaml:"groups"`
+	Labels                         map[string]string `yaml:"labels"`
+	Width                          string            `yaml:"width,omitempty"`
+	MaxWidth                       string            `yaml:"maxWidth,omitempty"` // Ajout du champ MaxWidth
+	FormBackgroundColor            string            `yaml:"formBackgroundColor,omitempty"`
+	PageBackgroundColor            string            `yaml:"pageBackgroundColor,omitempty"`
+	TabInactiveBackgroundColor     string            `yaml:"tabInactiveBackgroundColor,omitempty"`     // Nouveau champ
+	TabActiveBackgroundColor       string            `yaml:"tabActiveBackgroundColor,omitempty"`       // Nouveau champ
+	TabContentBackgroundColor      string            `yaml:"tabContentBackgroundColor,omitempty"`      // Nouveau champ
+	LabelColumnWidth               string            `yaml:"labelColumnWidth,omitempty"`               // Nouveau champ
+	TabLabelFontSize               string            `yaml:"tabLabelFontSize,omitempty"`               // Nouveau champ
+	ButtonFontSize                 string            `yaml:"buttonFontSize,omitempty"`                 // Nouveau champ
+	FormActionButtonsFontSize      string            `yaml:"formActionButtonsFontSize,omitempty"`      // Nouveau champ
+	FormContentMaxHeightAdjustment string            `yaml:"formContentMaxHeightAdjustment,omitempty"` // Nouveau champ
+	DuplicateExclude               []string          `yaml:"duplicateExclude,omitempty"`               // Champs non recopiés par "Dupliquer"
+	SubForms                       []SubFormConfig   `yaml:"subForms,omitempty"`                       // Lignes enfants saisies dans la fiche
+}
+
+// SubFormConfig déclare une sous-fiche : les lignes d'une entité enfant, affichées en tableau
+// dans la fiche du parent et enregistrées dans la même transaction que lui.
+type SubFormConfig struct {
+	Name       string   `yaml:"name"`
+	Label      string   `yaml:"label"`
+	Entity     string   `yaml:"entity"`     // Entité enfant (entity.name), dans la même source de données
+	ForeignKey string   `yaml:"foreignKey"` // Champ de l'enfant contenant l'id du parent
+	Columns    []string `yaml:"columns"`    // Champs de l'enfant saisis dans le tableau
+}
+
+// BulkConfig définit les actions groupées disponibles sur les lignes sélectionnées d'une liste.
+type BulkConfig struct {
+	Delete       bool     `yaml:"delete"`
+	Export       bool     `yaml:"export"`
+	UpdateFields []string `yaml:"updateFields"` // Champs modifiables en masse
+}
+
+// ListViewConfig est une vue prédéfinie d'une liste : filtres, tri et colonnes nommés.
+type ListViewConfig struct {
+	Name     string            `yaml:"name"`
+	Title    string            `yaml:"title"`
+	Sort     string            `yaml:"sort,omitempty"`
+	Order    string            `yaml:"order,omitempty"`
+	PageSize int               `yaml:"pageSize,omitempty"`
+	Search   string            `yaml:"search,omitempty"`
+	Filters  map[string]string `yaml:"filters,omitempty"` // Paramètres de filtre sans le préfixe "f_" (ex. cpt_ferme, cpt_solde_calcule_min)
+	Columns  []string          `yaml:"columns,omitempty"`
 }
 
 // ListConfig configuration pour la liste
 type ListConfig struct {
-	Name                       string            `yaml:"name"`
-	PageSize                   int               `yaml:"pageSize"`
-	DefaultSortField           string            `yaml:"defaultSortField"`
-	DefaultSortOrder           string            `yaml:"defaultSortOrder"`
-	PageSizeOptions            []int             `yaml:"pageSizeOptions"`
-	Columns                    []string          `yaml:"columns"`
-	SearchableFields           []string          `yaml:"searchableFields"`
-	SortableFields             []string          `yaml:"sortableFields"`
-	Labels                     map[string]string `yaml:"labels"`
-	Width                      string            `yaml:"width,omitempty"`
-	MaxWidth                   string            `yaml:"maxWidth,omitempty"`                   // Nouveau champ
-	FormBackgroundColor        string            `yaml:"formBackgroundColor,omitempty"`
-	PageBackgroundColor        string            `yaml:"pageBackgroundColor,omitempty"`
-	ButtonFontSize             string            `yaml:"buttonFontSize,omitempty"`             // Nouveau champ pour les listes
-	PaginationButtonFontSize   string            `yaml:"paginationButtonFontSize,omitempty"`   // Nouveau champ
-	PaginationTextFontSize     string            `yaml:"paginationTextFontSize,omitempty"`     // Nouveau champ
-	ColumnWidths               []string          `yaml:"columnWidths,omitempty"`               // Nouveau champ
-	ColumnHeaderFontSize       string            `yaml:"columnHeaderFontSize,omitempty"`       // Nouveau champ pour la taille de police des en-têtes de colonne
+	Name                     string            `yaml:"name"`
+	PageSize                 int               `yaml:"pageSize"`
+	DefaultSortField         string            `yaml:"defaultSortField"`
+	DefaultSortOrder         string            `yaml:"defaultSortOrder"`
+	PageSizeOptions          []int             `yaml:"pageSizeOptions"`
+	Columns                  []string          `yaml:"columns"`
+	SearchableFields         []string          `yaml:"searchableFields"`
+	SortableFields           []string          `yaml:"sortableFields"`
+	Labels                   map[string]string `yaml:"labels"`
+	Width                    string            `yaml:"width,omitempty"`
+	MaxWidth                 string            `yaml:"maxWidth,omitempty"` // Nouveau champ
+	FormBackgroundColor      string            `yaml:"formBackgroundColor,omitempty"`
+	PageBackgroundColor      string            `yaml:"pageBackgroundColor,omitempty"`
+	ButtonFontSize           string            `yaml:"buttonFontSize,omitempty"`           // Nouveau champ pour les listes
+	PaginationButtonFontSize string            `yaml:"paginationButtonFontSize,omitempty"` // Nouveau champ
+	PaginationTextFontSize   string            `yaml:"paginationTextFontSize,omitempty"`   // Nouveau champ
+	ColumnWidths             []string          `yaml:"columnWidths,omitempty"`             // Nouveau champ
+	ColumnHeaderFontSize     string            `yaml:"columnHeaderFontSize,omitempty"`     // Nouveau champ pour la taille de police des en-têtes de colonne
+	Bulk                     *BulkConfig       `yaml:"bulk,omitempty"`                     // Sélection multiple et actions groupées
+	FilterFields             []string          `yaml:"filterFields,omitempty"`             // Champs proposés dans la barre de filtres
+	Views                    []ListViewConfig  `yaml:"views,omitempty"`                    // Vues prédéfinies
+	Footer                   []AggregateConfig `yaml:"footer,omitempty"`                   // Agrégats affichés en pied de tableau
+	GroupBy                  string            `yaml:"groupBy,omitempty"`                  // Regroupement par défaut
+	GroupFields              []string          `yaml:"groupFields,omitempty"`              // Champs proposés au regroupement
+	Pagination               string            `yaml:"pagination,omitempty"`               // "offset" (défaut) ou "keyset" (par curseur)
+	ApproximateCount         bool              `yaml:"approximateCount,omitempty"`         // Mode keyset : afficher un total estimé
+}
+
+// ReferenceConfig décrit la table et la colonne référencées par un champ (clé étrangère logique).
+type ReferenceConfig struct {
+	Table    string `yaml:"table"`
+	Field    string `yaml:"field,omitempty"`    // Colonne référencée, "id" par défaut
+	OnDelete string `yaml:"onDelete,omitempty"` // "restrict" (défaut), "cascade" ou "nullify"
+	Message  string `yaml:"message,omitempty"`  // Message affiché si la valeur référencée n'existe pas
+	Display  string `yaml:"display,omitempty"`  // Colonne affichée dans les listes de choix (filtres)
+}
+
+// FullTextConfig active l'index plein texte (FTS5) d'une entité.
+type FullTextConfig struct {
+	Fields []string `yaml:"fields,omitempty"` // Champs indexés, list.searchableFields (hors id) par défaut
+}
+
+// UniqueConfig décrit une contrainte d'unicité portant sur un ou plusieurs champs.
+type UniqueConfig struct {
+	Fields  []string `yaml:"fields"`
+	Message string   `yaml:"message,omitempty"`
+}
+
+// AttachmentConfig limite les fichiers déposés dans un champ de type file ou image.
+type AttachmentConfig struct {
+	MaxSize   string   `yaml:"maxSize,omitempty"`   // Taille maximale : "500KB", "10MB" (défaut : 10MB)
+	Accept    []string `yaml:"accept,omitempty"`    // Types MIME acceptés ("application/pdf", "image/*") ; tous par défaut, images pour image
+	Thumbnail int      `yaml:"thumbnail,omitempty"` // Côté des vignettes d'images en pixels (défaut : 160)
+	MaxBytes  int64    `yaml:"-"`
+}
+
+// AcceptAttr renvoie les types acceptés pour l'attribut accept du champ de dépôt.
+func (a *AttachmentConfig) AcceptAttr() string {
+	return strings.Join(a.Accept, ",")
+}
+
+// MoneyConfig décrit un champ money : devise, mode de stockage et format d'affichage.
+type MoneyConfig struct {
+	Currency string `yaml:"currency"`           // Code ISO 4217 : EUR, USD, CHF...
+	Storage  string `yaml:"storage,omitempty"`  // "minor" (défaut : entier en unités mineures) ou "decimal" (colonne DECIMAL / NUMERIC)
+	Decimals *int   `yaml:"decimals,omitempty"` // Décimales de la devise (défaut : 2, 0 pour JPY)
+	Locale   string `yaml:"locale,omitempty"`   // Format d'affichage : "fr" (défaut, 1 846,67 €) ou "en" (€1,846.67)
+	Digits   int    `yaml:"-"`
+}
+
+// currencies donne le symbole et les décimales des devises courantes ; les autres
+// s'affichent avec leur code, sur 2 décimales.
+var currencies = map[string]struct {
+	Symbol   string
+	Decimals int
+}{
+	"EUR": {"€", 2}, "USD": {"$", 2}, "GBP": {"£", 2}, "CHF": {"CHF", 2},
+	"CAD": {"$ CA", 2}, "JPY": {"¥", 0}, "XOF": {"FCFA", 0}, "XPF": {"F CFP", 0},
+}
+
+// Symbol renvoie le symbole affiché à côté des montants.
+func (m *MoneyConfig) Symbol() string {
+	if c, ok := currencies[m.Currency]; ok {
+		return c.Symbol
+	}
+	return m.Currency
+}
+
+// EnumValue est une valeur autorisée d'un champ enum, avec son libellé et son badge.
+type EnumValue struct {
+	Value string `yaml:"value"`
+	Label string `yaml:"label"`
+	Color string `yaml:"color,omitempty"` // Couleur du badge : nom Bootstrap (success, danger...) ou couleur CSS
+	Icon  string `yaml:"icon,omitempty"`  // Symbole affiché devant le libellé
+}
+
+// bootstrapColors sont les couleurs de badge reprises des classes text-bg-* de Bootstrap.
+var bootstrapColors = []string{"primary", "secondary", "success", "danger", "warning", "info", "light", "dark"}
+
+// BadgeClass renvoie la classe Bootstrap du badge, vide pour une couleur CSS.
+func (v *EnumValue) BadgeClass() string {
+	if v.Color == "" {
+		return "text-bg-secondary"
+	}
+	if slices.Contains(bootstrapColors, v.Color) {
+		return "text-bg-" + v.Color
+	}
+	return ""
+}
+
+// EnumConfig déclare les valeurs autorisées d'un champ enum.
+type EnumConfig struct {
+	Widget string      `yaml:"widget,omitempty"` // Saisie dans la fiche : "select" (défaut) ou "radio"
+	Values []EnumValue `yaml:"values"`
+}
+
+// Option renvoie la valeur déclarée correspondant à v (valeur lue en base ou saisie), nil sinon.
+func (e *EnumConfig) Option(v interface{}) *EnumValue {
+	key := EnumKey(v)
+	for i := range e.Values {
+		if e.Values[i].Value == key {
+			return &e.Values[i]
+		}
+	}
+	return nil
+}
+
+// EnumKey normalise une valeur pour la comparer aux valeurs d'un enum : les booléens
+// deviennent "1" / "0", le texte lu en []byte une chaîne, nil une chaîne vide.
+func EnumKey(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(t)
+	case bool:
+		if t {
+			return "1"
+		}
+		return "0"
+	}
+	return fmt.Sprint(v)
 }
 
 // Field décrit un champ d'entité (modèle de données)
@@ -155,6 +328,12 @@ type Field struct {
 	DisplayFormat string
 	MaxLength     int
 	Align         string `yaml:"align,omitempty"` // Ajout de la propriété Align
+	Unique        bool
+	UniqueMessage string
+	References    *ReferenceConfig
+	Attachment    *AttachmentConfig // Champs file et image : la colonne contient l'identifiant de la pièce jointe
+	Enum          *EnumConfig       // Champs enum : valeurs autorisées et leurs libellés
+	Money         *MoneyConfig      // Champs money : devise et stockage des montants
 }
 
 // EntityConfig regroupe tout le config d’une entité
@@ -170,28 +349,117 @@ type EntityConfig struct {
 	List              ListConfig
 	Fiche             FicheConfig
 	VisionForms       map[string]VisionFormConfig
+	Uniques           []UniqueConfig // Contraintes d'unicité (simples et composites)
 	Code              *form_codes.FormCode
+	Triggers          *trigger.Set // Déclencheurs on_save / on_change compilés depuis le form_code
+	FullText          *FullTextConfig
+	DataSource        string   // Source de données nommée (config datasources), vide pour la base principale
+	PrimaryKey        []string // Colonnes de la clé primaire, ["id"] par défaut
+	KeyGeneration     *KeyGenerationConfig
+	History           bool // Versions complètes conservées à chaque modification (historique de la fiche)
+	Lock              *LockConfig
+}
+
+// LockConfig active le verrouillage des enregistrements ouverts en modification : un seul
+// utilisateur à la fois, les autres voient la fiche en lecture seule.
+type LockConfig struct {
+	Timeout  string        `yaml:"timeout,omitempty"` // Durée du verrou sans enregistrement ni annulation (défaut : 15m)
+	Duration time.Duration `yaml:"-"`
+}
+
+// KeyGenerationConfig décrit la génération de la clé des nouveaux enregistrements.
+// Strategy : "autoincrement" (clé entière attribuée par la base, défaut), "uuidv7", "ulid"
+// ou "sequence" (clé métier formatée, ex. "CPT-{year}-{seq:4}" -> CPT-2026-0001).
+type KeyGenerationConfig struct {
+	Strategy string `yaml:"strategy"`
+	Format   string `yaml:"format,omitempty"` // Stratégie sequence : {year}, {month}, {day} et {seq} ou {seq:N} (N chiffres)
+}
+
+// KeyColumns accepte une colonne (primaryKey: code) ou une liste (primaryKey: [agence, guichet]).
+type KeyColumns []string
+
+func (k *KeyColumns) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*k = KeyColumns{node.Value}
+		return nil
+	}
+	var cols []string
+	if err := node.Decode(&cols); err != nil {
+		return err
+	}
+	*k = cols
+	return nil
+}
+
+// HasAttachments indique si l'entité a des champs de type file ou image.
+func (ec *EntityConfig) HasAttachments() bool {
+	for _, f := range ec.Fields {
+		if f.Attachment != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPrimaryKey indique si le champ fait partie de la clé primaire.
+func (ec *EntityConfig) IsPrimaryKey(name string) bool {
+	return slices.Contains(ec.PrimaryKey, name)
+}
+
+// GeneratedKey indique si la clé n'est pas saisie : attribuée par la base ou par une stratégie de génération.
+func (ec *EntityConfig) GeneratedKey() bool {
+	return ec.AutoKey() || ec.KeyGeneration != nil && ec.KeyGeneration.Strategy != "autoincrement"
+}
+
+// IsGeneratedKey indique si le champ est la clé générée (non saisie en création).
+func (ec *EntityConfig) IsGeneratedKey(name string) bool {
+	return ec.GeneratedKey() && ec.IsPrimaryKey(name)
+}
+
+// AutoKey indique si la clé est générée par la base : une seule colonne de type entier.
+func (ec *EntityConfig) AutoKey() bool {
+	if len(ec.PrimaryKey) != 1 {
+		return false
+	}
+	switch ec.FieldsByName[ec.PrimaryKey[0]].Type {
+	case "uint", "int":
+		return true
+	}
+	return false
 }
 
 // yamlEntity reflète la structure des fichiers YAML
 type yamlEntity struct {
 	Entity struct {
-		Name            string `yaml:"name"`
-		Table           string `yaml:"table"`
-		Label           string `yaml:"label"`
-		LabelPlural     string `yaml:"labelPlural"`
-		DefaultPageSize int    `yaml:"defaultPageSize,omitempty"`
+		Name            string               `yaml:"name"`
+		Table           string               `yaml:"table"`
+		Label           string               `yaml:"label"`
+		LabelPlural     string               `yaml:"labelPlural"`
+		DefaultPageSize int                  `yaml:"defaultPageSize,omitempty"`
+		Unique          []UniqueConfig       `yaml:"unique,omitempty"`        // Contraintes d'unicité composites
+		FullText        *FullTextConfig      `yaml:"fullText,omitempty"`      // Recherche plein texte (SQLite FTS5)
+		DataSource      string               `yaml:"datasource,omitempty"`    // Source de données nommée (défaut : base principale)
+		PrimaryKey      KeyColumns           `yaml:"primaryKey,omitempty"`    // Clé primaire : colonne ou liste de colonnes (défaut : id)
+		KeyGeneration   *KeyGenerationConfig `yaml:"keyGeneration,omitempty"` // Génération de la clé des nouveaux enregistrements
+		History         bool                 `yaml:"history,omitempty"`       // Historique des versions de chaque enregistrement
+		Lock            *LockConfig          `yaml:"lock,omitempty"`          // Verrouillage pendant la modification
 	} `yaml:"entity"`
 	Fields []struct {
-		Name          string      `yaml:"name"`
-		Type          string      `yaml:"type,omitempty"`
-		Label         string      `yaml:"label"`
-		ReadOnly      bool        `yaml:"readonly,omitempty"`
-		Required      bool        `yaml:"required,omitempty"`
-		Default       interface{} `yaml:"default,omitempty"`
-		DisplayFormat string      `yaml:"displayFormat,omitempty"`
-		MaxLength     int         `yaml:"maxLength,omitempty"`
-		Align         string      `yaml:"align,omitempty"` // Ajout de la propriété Align
+		Name          string            `yaml:"name"`
+		Type          string            `yaml:"type,omitempty"`
+		Label         string            `yaml:"label"`
+		ReadOnly      bool              `yaml:"readonly,omitempty"`
+		Required      bool              `yaml:"required,omitempty"`
+		Default       interface{}       `yaml:"default,omitempty"`
+		DisplayFormat string            `yaml:"displayFormat,omitempty"`
+		MaxLength     int               `yaml:"maxLength,omitempty"`
+		Align         string            `yaml:"align,omitempty"` // Ajout de la propriété Align
+		Unique        bool              `yaml:"unique,omitempty"`
+		UniqueMessage string            `yaml:"uniqueMessage,omitempty"`
+		References    *ReferenceConfig  `yaml:"references,omitempty"`
+		Attachment    *AttachmentConfig `yaml:"attachment,omitempty"` // Limites des champs file et image
+		Enum          *EnumConfig       `yaml:"enum,omitempty"`       // Valeurs des champs enum
+		Money         *MoneyConfig      `yaml:"money,omitempty"`      // Devise des champs money
 	} `yaml:"fields"`
 	Forms []struct {
 		Name   string    `yaml:"name"`
@@ -213,6 +481,8 @@ func LoadEntityConfig(path string) (*EntityConfig, error) {
 		Label:             y.Entity.Label,
 		LabelPlural:       y.Entity.LabelPlural,
 		DefaultPageSize:   y.Entity.DefaultPageSize,
+		DataSource:        y.Entity.DataSource,
+		History:           y.Entity.History,
 		Fields:            make([]Field, len(y.Fields)),
 		FieldsByName:      make(map[string]Field),
 		FicheFieldsByName: make(map[string]FieldDef), // Initialisation
@@ -220,9 +490,86 @@ func LoadEntityConfig(path string) (*EntityConfig, error) {
 	}
 
 	for i, f := range y.Fields {
-		field := Field{f.Name, f.Label, f.Type, f.ReadOnly, f.Required, f.Default, f.DisplayFormat, f.MaxLength, f.Align} // Inclure f.Align
+		field := Field{
+			Name:          f.Name,
+			Label:         f.Label,
+			Type:          f.Type,
+			ReadOnly:      f.ReadOnly,
+			Required:      f.Required,
+			Default:       f.Default,
+			DisplayFormat: f.DisplayFormat,
+			MaxLength:     f.MaxLength,
+			Align:         f.Align,
+			Unique:        f.Unique,
+			UniqueMessage: f.UniqueMessage,
+			References:    f.References,
+		}
+		if f.Type == "file" || f.Type == "image" {
+			ac, err := attachmentConfig(f.Type, f.Attachment)
+			if err != nil {
+				return nil, fmt.Errorf("champ %s dans %s : %w", f.Name, path, err)
+			}
+			field.Attachment = ac
+		} else if f.Attachment != nil {
+			return nil, fmt.Errorf("champ %s dans %s : attachment réservé aux types file et image", f.Name, path)
+		}
+		if f.Type == "enum" {
+			if err := checkEnum(f.Enum); err != nil {
+				return nil, fmt.Errorf("champ %s dans %s : %w", f.Name, path, err)
+			}
+			field.Enum = f.Enum
+		} else if f.Enum != nil {
+			return nil, fmt.Errorf("champ %s dans %s : enum réservé au type enum", f.Name, path)
+		}
+		if f.Type == "money" {
+			if err := checkMoney(f.Money); err != nil {
+				return nil, fmt.Errorf("champ %s dans %s : %w", f.Name, path, err)
+			}
+			field.Money = f.Money
+		} else if f.Money != nil {
+			return nil, fmt.Errorf("champ %s dans %s : money réservé au type money", f.Name, path)
+		}
+		if field.References != nil {
+			if field.References.Field == "" {
+				field.References.Field = "id"
+			}
+			if field.References.OnDelete == "" {
+				field.References.OnDelete = "restrict"
+			}
+		}
 		ec.Fields[i] = field
 		ec.FieldsByName[f.Name] = field
+		if field.Unique {
+			ec.Uniques = append(ec.Uniques, UniqueConfig{Fields: []string{f.Name}, Message: f.UniqueMessage})
+		}
+	}
+	ec.Uniques = append(ec.Uniques, y.Entity.Unique...)
+
+	ec.PrimaryKey = y.Entity.PrimaryKey
+	if len(ec.PrimaryKey) == 0 {
+		ec.PrimaryKey = []string{"id"}
+	}
+	for _, name := range ec.PrimaryKey {
+		if _, ok := ec.FieldsByName[name]; !ok {
+			return nil, fmt.Errorf("primaryKey : champ inconnu %s dans %s", name, path)
+		}
+	}
+	if lc := y.Entity.Lock; lc != nil {
+		lc.Duration = 15 * time.Minute
+		if lc.Timeout != "" {
+			d, err := time.ParseDuration(lc.Timeout)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("lock.timeout invalide « %s » dans %s", lc.Timeout, path)
+			}
+			lc.Duration = d
+		}
+		ec.Lock = lc
+	}
+	if kg := y.Entity.KeyGeneration; kg != nil {
+		if err := checkKeyGeneration(ec, kg); err != nil {
+			return nil, fmt.Errorf("keyGeneration dans %s : %w", path, err)
+		}
+		ec.KeyGeneration = kg
 	}
 
 	for _, form := range y.Forms {
@@ -233,6 +580,9 @@ func LoadEntityConfig(path string) (*EntityConfig, error) {
 				return nil, fmt.Errorf("erreur décodage 'list' form %s: %w", form.Name, err)
 			}
 			listCfg.Name = form.Name
+			if err := checkFooter(form.Name, listCfg.Footer); err != nil {
+				return nil, err
+			}
 			ec.List = listCfg
 		case "fiche":
 			var ficheCfg FicheConfig
@@ -240,6 +590,11 @@ func LoadEntityConfig(path string) (*EntityConfig, error) {
 				return nil, fmt.Errorf("erreur décodage 'fiche' form %s: %w", form.Name, err)
 			}
 			ficheCfg.Name = form.Name
+			for _, sf := range ficheCfg.SubForms {
+				if sf.Name == "" || sf.Entity == "" || sf.ForeignKey == "" || strings.Contains(sf.Name, ".") {
+					return nil, fmt.Errorf("sous-fiche invalide dans %s : name (sans point), entity et foreignKey sont obligatoires", form.Name)
+				}
+			}
 			ec.Fiche = ficheCfg
 		case "vision":
 			var visionCfg VisionFormConfig
@@ -248,10 +603,20 @@ func LoadEntityConfig(path string) (*EntityConfig, error) {
 			}
 			visionCfg.Name = form.Name
 			visionCfg.Type = form.Type
+			if err := checkFooter(form.Name, visionCfg.Footer); err != nil {
+				return nil, err
+			}
 			ec.VisionForms[form.Name] = visionCfg
 		}
 	}
 
+	if ec.List.Pagination == "keyset" && len(ec.PrimaryKey) > 1 {
+		return nil, fmt.Errorf("pagination keyset : clé primaire composite non prise en charge dans %s", path)
+	}
+	if len(ec.Fiche.SubForms) > 0 && len(ec.PrimaryKey) > 1 {
+		return nil, fmt.Errorf("sous-fiches : clé primaire composite du parent non prise en charge dans %s", path)
+	}
+
 	// Remplir la map FicheFieldsByName après avoir chargé la fiche
 	for _, group := range ec.Fiche.Groups {
 		for _, fieldDef := range group.Fields {
@@ -268,12 +633,31 @@ func LoadEntityConfig(path string) (*EntityConfig, error) {
 		}
 	}
 	if ec.List.DefaultSortField == "" && len(ec.Fields) > 0 {
-			ec.List.DefaultSortField = ec.Fields[0].Name
+		ec.List.DefaultSortField = ec.Fields[0].Name
 	}
 	if ec.List.DefaultSortOrder == "" {
 		ec.List.DefaultSortOrder = "asc"
 	}
 
+	// Index plein texte : champs de recherche de la liste (hors clé) par défaut, tous déclarés dans l'entité
+	if ft := y.Entity.FullText; ft != nil {
+		if len(ft.Fields) == 0 {
+			for _, name := range ec.List.SearchableFields {
+				if !ec.IsPrimaryKey(name) {
+					ft.Fields = append(ft.Fields, name)
+				}
+			}
+		}
+		for _, name := range ft.Fields {
+			if _, ok := ec.FieldsByName[name]; !ok {
+				return nil, fmt.Errorf("fullText : champ inconnu %s dans %s", name, path)
+			}
+		}
+		if len(ft.Fields) > 0 {
+			ec.FullText = ft
+		}
+	}
+
 	// Charger le form_code si existant
 	codePath := filepath.Join("config", "form_codes", ec.Fiche.Name+"_code.yaml")
 	if fc, err := form_codes.LoadFormCode(codePath); err != nil {
@@ -282,5 +666,165 @@ func LoadEntityConfig(path string) (*EntityConfig, error) {
 		ec.Code = fc
 	}
 
+	// Compiler (et typer) les déclencheurs du form_code dès le chargement
+	fieldTypes := make(map[string]string, len(ec.Fields))
+	for _, f := range ec.Fields {
+		fieldTypes[f.Name] = f.Type
+		if f.Money != nil { // Montants vus tels que stockés : centimes entiers ou texte décimal
+			fieldTypes[f.Name] = "string"
+			if f.Money.Storage == "minor" {
+				fieldTypes[f.Name] = "int"
+			}
+		}
+	}
+	triggers, err := trigger.Compile(ec.Code, fieldTypes)
+	if err != nil {
+		return nil, fmt.Errorf("déclencheurs invalides dans %s : %w", codePath, err)
+	}
+	ec.Triggers = triggers
+
 	return ec, nil
 }
+
+// checkEnum vérifie les valeurs d'un champ enum : au moins une, sans doublon, avec un libellé.
+func checkEnum(e *EnumConfig) error {
+	if e == nil || len(e.Values) == 0 {
+		return fmt.Errorf("enum.values est obligatoire pour le type enum")
+	}
+	switch e.Widget {
+	case "", "select", "radio":
+	default:
+		return fmt.Errorf("enum.widget « %s » inconnu (select ou radio)", e.Widget)
+	}
+	seen := make(map[string]bool, len(e.Values))
+	for i, v := range e.Values {
+		if seen[v.Value] {
+			return fmt.Errorf("valeur « %s » déclarée deux fois dans enum.values", v.Value)
+		}
+		seen[v.Value] = true
+		if v.Label == "" {
+			e.Values[i].Label = v.Value
+		}
+	}
+	return nil
+}
+
+// checkMoney vérifie la devise d'un champ money et complète le stockage et les décimales.
+func checkMoney(m *MoneyConfig) error {
+	if m == nil || m.Currency == "" {
+		return fmt.Errorf("money.currency est obligatoire pour le type money")
+	}
+	m.Currency = strings.ToUpper(m.Currency)
+	if len(m.Currency) != 3 || strings.Trim(m.Currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return fmt.Errorf("money.currency « %s » invalide (code ISO 4217 sur 3 lettres)", m.Currency)
+	}
+	switch m.Storage {
+	case "":
+		m.Storage = "minor"
+	case "minor", "decimal":
+	default:
+		return fmt.Errorf("money.storage « %s » inconnu (minor ou decimal)", m.Storage)
+	}
+	switch m.Locale {
+	case "":
+		m.Locale = "fr"
+	case "fr", "en":
+	default:
+		return fmt.Errorf("money.locale « %s » inconnue (fr ou en)", m.Locale)
+	}
+	m.Digits = 2
+	if c, ok := currencies[m.Currency]; ok {
+		m.Digits = c.Decimals
+	}
+	if m.Decimals != nil {
+		if *m.Decimals < 0 || *m.Decimals > 6 {
+			return fmt.Errorf("money.decimals doit être compris entre 0 et 6")
+		}
+		m.Digits = *m.Decimals
+	}
+	return nil
+}
+
+// attachmentConfig complète les limites d'un champ file ou image : 10 Mo par défaut,
+// et pour image les seuls formats dont une vignette peut être calculée.
+func attachmentConfig(fieldType string, ac *AttachmentConfig) (*AttachmentConfig, error) {
+	if ac == nil {
+		ac = &AttachmentConfig{}
+	}
+	ac.MaxBytes = 10 << 20
+	if ac.MaxSize != "" {
+		n, err := parseSize(ac.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		ac.MaxBytes = n
+	}
+	if len(ac.Accept) == 0 && fieldType == "image" {
+		ac.Accept = []string{"image/jpeg", "image/png", "image/gif"}
+	}
+	if ac.Thumbnail <= 0 {
+		ac.Thumbnail = 160
+	}
+	return ac, nil
+}
+
+// parseSize lit une taille en octets : "2048", "500KB", "10MB", "1GB" (ou Ko, Mo, Go).
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"GB", 1 << 30}, {"GO", 1 << 30}, {"MB", 1 << 20}, {"MO", 1 << 20},
+		{"KB", 1 << 10}, {"KO", 1 << 10}, {"B", 1}, {"O", 1},
+	}
+	v := strings.ToUpper(strings.TrimSpace(s))
+	factor := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(v, u.suffix) {
+			v, factor = strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), u.factor
+			break
+		}
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("maxSize invalide « %s »", s)
+	}
+	return n * factor, nil
+}
+
+// checkKeyGeneration vérifie la stratégie de génération de clé : une clé entière pour
+// autoincrement, une clé texte d'une seule colonne pour les autres stratégies.
+func checkKeyGeneration(ec *EntityConfig, kg *KeyGenerationConfig) error {
+	switch kg.Strategy {
+	case "autoincrement":
+		if !ec.AutoKey() {
+			return fmt.Errorf("autoincrement exige une clé d'une seule colonne de type uint ou int")
+		}
+		return nil
+	case "uuidv7", "ulid", "sequence":
+	default:
+		return fmt.Errorf("stratégie « %s » inconnue (autoincrement, uuidv7, ulid ou sequence)", kg.Strategy)
+	}
+	if len(ec.PrimaryKey) != 1 || ec.FieldsByName[ec.PrimaryKey[0]].Type != "string" {
+		return fmt.Errorf("%s exige une clé d'une seule colonne de type string", kg.Strategy)
+	}
+	if kg.Strategy == "sequence" && !strings.Contains(kg.Format, "{seq") {
+		return fmt.Errorf("sequence exige un format contenant {seq} ou {seq:N}")
+	}
+	return nil
+}
+
+// checkFooter vérifie les fonctions d'agrégat déclarées dans le pied d'une liste.
+func checkFooter(form string, footer []AggregateConfig) error {
+	for _, agg := range footer {
+		switch agg.Func {
+		case "sum", "avg", "min", "max", "count":
+		default:
+			return fmt.Errorf("agrégat « %s » inconnu pour %s.%s (sum, avg, min, max ou count)", agg.Func, form, agg.Field)
+		}
+		if agg.Field == "" {
+			return fmt.Errorf("agrégat sans champ dans le pied de %s", form)
+		}
+	}
+	return nil
+}
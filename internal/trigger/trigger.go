@@ -0,0 +1,307 @@
+// internal/trigger/trigger.go
+package trigger
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"example.com/go-crud/config/form_codes"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// dbFormat est le format de stockage des champs datetime.
+const dbFormat = "2006-01-02 15:04:05"
+
+// costLimit borne le coût d'évaluation d'une expression (bac à sable).
+const costLimit = 10000
+
+// assignment est une affectation "champ = expression" compilée.
+type assignment struct {
+	field   string
+	program cel.Program
+}
+
+// rule est la version compilée d'un form_codes.TriggerRule.
+type rule struct {
+	field      string
+	when       cel.Program // nil si pas de condition
+	sets       []assignment
+	err        string
+	errorField string
+}
+
+// Set regroupe les déclencheurs compilés d'un formulaire.
+type Set struct {
+	fieldTypes map[string]string
+	onSave     []rule
+	onChange   []rule
+}
+
+// Compile analyse et vérifie le typage des déclencheurs on_save / on_change d'un form_code.
+// fieldTypes associe chaque champ de l'entité à son type YAML (string, number, boolean...).
+// Renvoie nil si le form_code ne déclare aucun déclencheur.
+func Compile(fc *form_codes.FormCode, fieldTypes map[string]string) (*Set, error) {
+	if fc == nil || (len(fc.OnSave) == 0 && len(fc.OnChange) == 0) {
+		return nil, nil
+	}
+
+	opts := []cel.EnvOption{
+		cel.Variable("previous", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Function("now",
+			cel.Overload("now_timestamp", nil, cel.TimestampType,
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					return types.Timestamp{Time: time.Now()}
+				}),
+			),
+		),
+	}
+	for name, typ := range fieldTypes {
+		opts = append(opts, cel.Variable(name, celType(typ)))
+	}
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("environnement d'expressions : %w", err)
+	}
+
+	s := &Set{fieldTypes: fieldTypes}
+	if s.onSave, err = compileRules(env, fc.OnSave, fieldTypes, "on_save", false); err != nil {
+		return nil, err
+	}
+	if s.onChange, err = compileRules(env, fc.OnChange, fieldTypes, "on_change", true); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// compileRules compile une section de déclencheurs.
+func compileRules(env *cel.Env, rules []form_codes.TriggerRule, fieldTypes map[string]string, section string, needField bool) ([]rule, error) {
+	var out []rule
+	for i, r := range rules {
+		where := fmt.Sprintf("%s[%d]", section, i)
+		if needField {
+			if _, ok := fieldTypes[r.Field]; !ok {
+				return nil, fmt.Errorf("%s : champ surveillé inconnu « %s »", where, r.Field)
+			}
+		}
+		if len(r.Set) == 0 && r.Error == "" {
+			return nil, fmt.Errorf("%s : ni 'set' ni 'error' n'est défini", where)
+		}
+
+		cr := rule{field: r.Field, err: r.Error, errorField: r.ErrorField}
+		if cr.errorField == "" {
+			cr.errorField = r.Field
+		}
+		if cr.err != "" && cr.errorField == "" {
+			return nil, fmt.Errorf("%s : 'error_field' est requis avec 'error'", where)
+		}
+		if r.When != "" {
+			prg, err := compile(env, r.When, cel.BoolType)
+			if err != nil {
+				return nil, fmt.Errorf("%s.when : %w", where, err)
+			}
+			cr.when = prg
+		}
+
+		// Ordre stable des affectations
+		targets := make([]string, 0, len(r.Set))
+		for f := range r.Set {
+			targets = append(targets, f)
+		}
+		sort.Strings(targets)
+		for _, f := range targets {
+			typ, ok := fieldTypes[f]
+			if !ok {
+				return nil, fmt.Errorf("%s.set : champ inconnu « %s »", where, f)
+			}
+			prg, err := compile(env, r.Set[f], celType(typ))
+			if err != nil {
+				return nil, fmt.Errorf("%s.set.%s : %w", where, f, err)
+			}
+			cr.sets = append(cr.sets, assignment{field: f, program: prg})
+		}
+		out = append(out, cr)
+	}
+	return out, nil
+}
+
+// compile analyse une expression et vérifie que son type est compatible avec want.
+func compile(env *cel.Env, src string, want *cel.Type) (cel.Program, error) {
+	ast, iss := env.Compile(src)
+	if iss != nil && iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	if !want.IsAssignableType(ast.OutputType()) {
+		return nil, fmt.Errorf("« %s » est de type %s, %s attendu", src, ast.OutputType(), want)
+	}
+	return env.Program(ast, cel.CostLimit(costLimit))
+}
+
+// Apply évalue les déclencheurs : values est modifiée en place par les affectations,
+// previous est l'enregistrement avant modification (nil en création).
+// Renvoie les erreurs par champ levées par les règles 'error'.
+func (s *Set) Apply(values, previous map[string]interface{}) (map[string]string, error) {
+	errors := make(map[string]string)
+	if s == nil {
+		return errors, nil
+	}
+
+	var rules []rule
+	for _, r := range s.onChange {
+		if s.changed(r.field, values, previous) {
+			rules = append(rules, r)
+		}
+	}
+	rules = append(rules, s.onSave...)
+
+	for _, r := range rules {
+		vars := s.activation(values, previous)
+		if r.when != nil {
+			out, _, err := r.when.Eval(vars)
+			if err != nil {
+				return nil, fmt.Errorf("évaluation de la condition : %w", err)
+			}
+			if ok, _ := out.Value().(bool); !ok {
+				continue
+			}
+		}
+		if r.err != "" {
+			errors[r.errorField] = r.err
+			continue
+		}
+		for _, a := range r.sets {
+			out, _, err := a.program.Eval(vars)
+			if err != nil {
+				return nil, fmt.Errorf("évaluation de %s : %w", a.field, err)
+			}
+			values[a.field] = fromCEL(out, s.fieldTypes[a.field])
+		}
+	}
+	return errors, nil
+}
+
+// changed indique si la valeur du champ diffère entre l'enregistrement précédent et les valeurs saisies.
+func (s *Set) changed(field string, values, previous map[string]interface{}) bool {
+	cur, ok := values[field]
+	if !ok {
+		return false
+	}
+	var prev interface{}
+	if previous != nil {
+		prev = previous[field]
+	}
+	typ := s.fieldTypes[field]
+	return fmt.Sprint(toCEL(cur, typ)) != fmt.Sprint(toCEL(prev, typ))
+}
+
+// activation construit les variables visibles par les expressions : chaque champ
+// (valeur saisie, sinon valeur précédente, sinon valeur zéro du type) et la map previous.
+func (s *Set) activation(values, previous map[string]interface{}) map[string]interface{} {
+	vars := make(map[string]interface{}, len(s.fieldTypes)+1)
+	prev := make(map[string]interface{})
+	for name, typ := range s.fieldTypes {
+		var raw interface{}
+		if v, ok := values[name]; ok {
+			raw = v
+		} else if previous != nil {
+			raw = previous[name]
+		}
+		vars[name] = toCEL(raw, typ)
+		if previous != nil && previous[name] != nil {
+			prev[name] = toCEL(previous[name], typ)
+		}
+	}
+	vars["previous"] = prev
+	return vars
+}
+
+// celType associe un type de champ YAML à un type CEL.
+func celType(fieldType string) *cel.Type {
+	switch fieldType {
+	case "uint", "int":
+		return cel.IntType
+	case "number":
+		return cel.DoubleType
+	case "boolean":
+		return cel.BoolType
+	case "date", "datetime":
+		return cel.TimestampType
+	default:
+		return cel.StringType
+	}
+}
+
+// toCEL convertit une valeur issue du formulaire ou de la base vers le type CEL du champ.
+// Une valeur nulle devient la valeur zéro du type.
+func toCEL(v interface{}, fieldType string) interface{} {
+	if b, ok := v.([]byte); ok { // Texte lu en base (MySQL)
+		v = string(b)
+	}
+	s := ""
+	if v != nil {
+		s = fmt.Sprint(v)
+	}
+	switch fieldType {
+	case "uint", "int":
+		switch n := v.(type) {
+		case int:
+			return int64(n)
+		case int64:
+			return n
+		case float64:
+			return int64(n)
+		}
+		i, _ := strconv.ParseInt(s, 10, 64)
+		return i
+	case "number":
+		switch n := v.(type) {
+		case float64:
+			return n
+		case int64:
+			return float64(n)
+		case int:
+			return float64(n)
+		}
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	case "boolean":
+		switch b := v.(type) {
+		case bool:
+			return b
+		case int64:
+			return b != 0
+		}
+		return s == "true" || s == "1" || s == "on"
+	case "date", "datetime":
+		if t, ok := v.(time.Time); ok {
+			return t
+		}
+		for _, layout := range []string{dbFormat, "2006-01-02", time.RFC3339} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t
+			}
+		}
+		return time.Time{}
+	default:
+		return s
+	}
+}
+
+// fromCEL convertit le résultat d'une expression vers le format de stockage du champ.
+func fromCEL(v ref.Val, fieldType string) interface{} {
+	native := v.Value()
+	switch fieldType {
+	case "datetime":
+		if t, ok := native.(time.Time); ok {
+			return t.Format(dbFormat)
+		}
+	case "date":
+		if t, ok := native.(time.Time); ok {
+			return t
+		}
+	}
+	return native
+}
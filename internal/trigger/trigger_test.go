@@ -0,0 +1,176 @@
+// internal/trigger/trigger_test.go
+package trigger
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/go-crud/config/form_codes"
+)
+
+var testFields = map[string]string{
+	"libelle":  "string",
+	"quantite": "int",
+	"prix":     "number",
+	"total":    "number",
+	"ferme":    "boolean",
+	"cloture":  "date",
+	"maj":      "datetime",
+	"statut":   "string",
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fc   form_codes.FormCode
+		want string // Extrait du message d'erreur
+	}{
+		{"syntaxe", form_codes.FormCode{OnSave: []form_codes.TriggerRule{{Set: map[string]string{"total": "prix *"}}}}, "on_save[0].set.total"},
+		{"variable inconnue", form_codes.FormCode{OnSave: []form_codes.TriggerRule{{Set: map[string]string{"total": "inconnu * 2.0"}}}}, "inconnu"},
+		{"type du résultat", form_codes.FormCode{OnSave: []form_codes.TriggerRule{{Set: map[string]string{"total": "libelle"}}}}, "attendu"},
+		{"champ affecté inconnu", form_codes.FormCode{OnSave: []form_codes.TriggerRule{{Set: map[string]string{"nope": "1"}}}}, "champ inconnu « nope »"},
+		{"condition non booléenne", form_codes.FormCode{OnSave: []form_codes.TriggerRule{{When: "quantite", Error: "x", ErrorField: "quantite"}}}, "on_save[0].when"},
+		{"règle vide", form_codes.FormCode{OnSave: []form_codes.TriggerRule{{When: "ferme"}}}, "ni 'set' ni 'error'"},
+		{"erreur sans champ", form_codes.FormCode{OnSave: []form_codes.TriggerRule{{When: "ferme", Error: "x"}}}, "'error_field' est requis"},
+		{"champ surveillé inconnu", form_codes.FormCode{OnChange: []form_codes.TriggerRule{{Field: "nope", Error: "x"}}}, "on_change[0] : champ surveillé inconnu"},
+	}
+	for _, tc := range tests {
+		_, err := Compile(&tc.fc, testFields)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s : Compile = %v, attendu une erreur contenant %q", tc.name, err, tc.want)
+		}
+	}
+	if s, err := Compile(&form_codes.FormCode{}, testFields); s != nil || err != nil {
+		t.Errorf("Compile sans déclencheur = %v, %v", s, err)
+	}
+	if s, err := Compile(nil, testFields); s != nil || err != nil {
+		t.Errorf("Compile(nil) = %v, %v", s, err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	fc := &form_codes.FormCode{
+		OnSave: []form_codes.TriggerRule{
+			{Set: map[string]string{"total": "double(quantite) * prix"}},
+			{When: "ferme && cloture == timestamp('0001-01-01T00:00:00Z')", Error: "Date de clôture obligatoire", ErrorField: "cloture"},
+			{When: "quantite < 0", Error: "Quantité négative", ErrorField: "quantite"},
+		},
+		OnChange: []form_codes.TriggerRule{
+			{Field: "libelle", Set: map[string]string{"maj": "timestamp('2026-01-02T03:04:05Z')"}},
+			{Field: "statut", When: "has(previous.statut) && previous.statut == 'clos'", Error: "Un dossier clos ne se rouvre pas", ErrorField: "statut"},
+		},
+	}
+	s, err := Compile(fc, testFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		values   map[string]interface{}
+		previous map[string]interface{}
+		want     map[string]interface{} // Valeurs attendues après Apply (champs vérifiés)
+		errors   map[string]string
+	}{
+		{
+			name:   "création : calcul et changement de libellé",
+			values: map[string]interface{}{"libelle": "A", "quantite": "3", "prix": "2.5"},
+			want:   map[string]interface{}{"total": 7.5, "maj": "2026-01-02 03:04:05"},
+			errors: map[string]string{},
+		},
+		{
+			name:     "modification : valeurs précédentes pour les champs non saisis",
+			values:   map[string]interface{}{"libelle": "A", "prix": 4.0},
+			previous: map[string]interface{}{"libelle": "A", "quantite": int64(2), "prix": 1.0},
+			want:     map[string]interface{}{"total": 8.0, "maj": nil},
+			errors:   map[string]string{},
+		},
+		{
+			name:   "erreurs par champ",
+			values: map[string]interface{}{"quantite": "-1", "ferme": "on"},
+			want:   map[string]interface{}{"total": 0.0},
+			errors: map[string]string{"cloture": "Date de clôture obligatoire", "quantite": "Quantité négative"},
+		},
+		{
+			name:   "condition fausse avec une date saisie",
+			values: map[string]interface{}{"quantite": "1", "ferme": "1", "cloture": "2026-03-31"},
+			errors: map[string]string{},
+		},
+		{
+			name:     "on_change : champ modifié",
+			values:   map[string]interface{}{"statut": "ouvert"},
+			previous: map[string]interface{}{"statut": "clos"},
+			errors:   map[string]string{"statut": "Un dossier clos ne se rouvre pas"},
+		},
+		{
+			name:     "on_change : champ inchangé",
+			values:   map[string]interface{}{"statut": "clos"},
+			previous: map[string]interface{}{"statut": "clos"},
+			errors:   map[string]string{},
+		},
+	}
+	for _, tc := range tests {
+		errs, err := s.Apply(tc.values, tc.previous)
+		if err != nil {
+			t.Errorf("%s : Apply : %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(errs, tc.errors) {
+			t.Errorf("%s : erreurs %v, attendu %v", tc.name, errs, tc.errors)
+		}
+		for field, want := range tc.want {
+			if got := tc.values[field]; !reflect.DeepEqual(got, want) {
+				t.Errorf("%s : %s = %#v, attendu %#v", tc.name, field, got, want)
+			}
+		}
+	}
+
+	var none *Set
+	if errs, err := none.Apply(map[string]interface{}{}, nil); err != nil || len(errs) != 0 {
+		t.Errorf("Apply sur un Set nil = %v, %v", errs, err)
+	}
+}
+
+func TestCostLimit(t *testing.T) {
+	fc := &form_codes.FormCode{OnSave: []form_codes.TriggerRule{
+		{Set: map[string]string{"libelle": "[1,2,3,4,5,6,7,8,9,10].map(a, [1,2,3,4,5,6,7,8,9,10].map(b, [1,2,3,4,5,6,7,8,9,10].map(c, [1,2,3,4,5,6,7,8,9,10].map(d, a+b+c+d)))).size() > 0 ? 'x' : 'y'"}},
+	}}
+	s, err := Compile(fc, testFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Apply(map[string]interface{}{}, nil); err == nil {
+		t.Error("Apply : expression trop coûteuse évaluée sans erreur")
+	}
+}
+
+func TestToCEL(t *testing.T) {
+	day := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		v    interface{}
+		typ  string
+		want interface{}
+	}{
+		{"12", "int", int64(12)},
+		{12.9, "int", int64(12)},
+		{nil, "int", int64(0)},
+		{"x", "int", int64(0)},
+		{"2,5", "number", 0.0}, // La virgule n'est pas lue : le formulaire convertit avant
+		{int64(3), "number", 3.0},
+		{"on", "boolean", true},
+		{int64(0), "boolean", false},
+		{"", "boolean", false},
+		{"2026-03-31", "date", day},
+		{"2026-03-31 00:00:00", "datetime", day},
+		{"", "date", time.Time{}},
+		{[]byte("abc"), "string", "abc"},
+		{[]byte("42"), "int", int64(42)},
+		{nil, "string", ""},
+	}
+	for _, tc := range tests {
+		if got := toCEL(tc.v, tc.typ); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("toCEL(%#v, %s) = %#v, attendu %#v", tc.v, tc.typ, got, tc.want)
+		}
+	}
+}
@@ -17,9 +17,9 @@ import (
 	"example.com/go-crud/config"
 	"example.com/go-crud/internal/admin" // Import du nouveau package admin
 	"example.com/go-crud/internal/crud"
+	"example.com/go-crud/internal/database"
 	"example.com/go-crud/internal/entity"
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -32,14 +32,42 @@ func main() {
 	}
 
 	// 2) Ouvrir la DB (utilise la variable `cfg` locale)
-	dbPath := filepath.Join(cfg.Database.Directory, cfg.Database.Name)
-	log.Printf("Connecting SQLite at %s", dbPath)
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	// La recherche plein texte (entity.fullText) demande le module FTS5 : go build -tags sqlite_fts5
+	// (voir README.md ; sans le tag, un avertissement [FTS] est journalisé par entité concernée)
+	// (database.driver : sqlite par défaut, postgres ou mysql avec database.dsn)
+	// (datasources : sources nommées supplémentaires, choisies par entity.datasource)
+	sources, err := database.OpenAll(cfg, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 	if err != nil {
-		log.Fatalf("Impossible d'ouvrir SQLite : %v", err)
+		log.Fatalf("Impossible d'ouvrir la base de données : %v", err)
 	}
+	// Les tables techniques (vues, colonnes...) sont créées dans chaque source
+	crud.RegisterDataSource(database.DefaultSource, sources.Default)
+	if err := crud.Migrate(sources.Default); err != nil {
+		log.Fatalf("Erreur de création des tables techniques : %v", err)
+	}
+	for name, db := range sources.Named {
+		crud.RegisterDataSource(name, db)
+		if err := crud.Migrate(db); err != nil {
+			log.Fatalf("Erreur de création des tables techniques (source %s) : %v", name, err)
+		}
+	}
+
+	// Utilisateur courant : Basic Auth vérifiée sur admin et users, ou X-Remote-User d'un proxy de confiance
+	crud.SetAdminUser(cfg.Admin.Username)
+	accounts := make(map[string]string)
+	if cfg.Admin.Username != "" {
+		accounts[cfg.Admin.Username] = cfg.Admin.Password
+	}
+	for _, u := range cfg.Users {
+		accounts[u.Username] = u.Password
+	}
+	crud.SetAccounts(accounts)
+	if err := crud.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Erreur config proxies de confiance : %v", err)
+	}
+	crud.SetFileStore(crud.NewLocalStore(cfg.Storage.Directory))
 
 	// 3) Configurer le router (on passe `cfg` en paramètre)
 	router := setupRouter(cfg)
@@ -49,7 +77,7 @@ func main() {
 	adminRoutes := router.Group("/admin")
 	{
 		// On applique le middleware de sécurité à tout le groupe /admin
-		adminRoutes.Use(admin.AuthMiddleware())
+		adminRoutes.Use(admin.AuthMiddleware(cfg))
 
 		// On définit ensuite les routes du groupe
 		adminRoutes.GET("/settings", admin.GetSettingsHandler(cfg))
@@ -69,6 +97,7 @@ func main() {
 	}
 
 	// 5) Enregistrer chaque entité CRUD
+	// (les hooks métier éventuels s'ajoutent avec crud.RegisterHooks avant le démarrage du serveur)
 	for _, file := range files {
 		log.Printf("load entity: %s", file)
 		ec, err := entity.LoadEntityConfig(file)
@@ -76,8 +105,14 @@ func main() {
 			log.Printf("skip entity %s: %v", file, err)
 			continue
 		}
+		db, err := sources.Get(ec.DataSource)
+		if err != nil {
+			log.Printf("skip entity %s: %v", file, err)
+			continue
+		}
 		crud.RegisterEntity(router, db, ec)
 	}
+	crud.RegisterSearch(router)
 
 	// 6) Démarrer le serveur avec graceful shutdown
 	addr := fmt.Sprintf(":%s", cfg.Server.Port)
@@ -107,7 +142,7 @@ func main() {
 func setupRouter(cfg *config.Config) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
-	r.Use(gin.Logger(), gin.Recovery())
+	r.Use(gin.Logger(), gin.Recovery(), crud.Identify())
 
 	// 1) Définir les fonctions de template AVANT de charger les HTML
 	r.SetFuncMap(template.FuncMap{